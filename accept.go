@@ -0,0 +1,102 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Accept describes the Accept header
+const Accept string = "Accept"
+
+// negotiable describes the media types served by Negotiate in the order of preference for wildcards
+var negotiable = []struct {
+	mediaTypes []string
+	render     func(r *Render, w http.ResponseWriter, status int, v interface{}) error
+}{
+	{[]string{ContentJSON}, (*Render).JSON},
+	{[]string{ContentXML, "text/xml"}, (*Render).XML},
+	{[]string{ContentYAML, "application/yaml", "text/yaml", "text/x-yaml"}, (*Render).YAML},
+}
+
+// acceptItem describes a value of an Accept like header with its quality
+type acceptItem struct {
+	value string
+	q     float64
+}
+
+// parseAccept parse an Accept like header and return the values sorted by quality in descending order,
+// values with q=0 are dropped and values with equal quality keep the header order
+func parseAccept(header string) []acceptItem {
+	var items []acceptItem
+	for _, item := range parseAcceptItems(header) {
+		if item.q > 0 {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	return items
+}
+
+// parseAcceptItems parse an Accept like header and return the values in the header order including q=0
+func parseAcceptItems(header string) []acceptItem {
+	var items []acceptItem
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		items = append(items, acceptItem{value: value, q: q})
+	}
+	return items
+}
+
+// matchMediaType report whether the media range of an Accept header e.g: application/* matches the media type
+func matchMediaType(mediaRange, mt string) bool {
+	return mediaRange == mt || mediaRange == "*/*" ||
+		strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(mediaRange, "*"))
+}
+
+// Negotiate serve the data as JSON, XML or YAML picked from the Accept header of the request by quality,
+// JSON is served when nothing matches or */* is sent. A media type sent with q=0 is excluded from the
+// wildcards e.g: application/json;q=0, */* serves XML
+func (r *Render) Negotiate(w http.ResponseWriter, req *http.Request, status int, v interface{}) error {
+	w.Header().Add(Vary, Accept)
+	rr := r.WithRequest(req)
+	header := req.Header.Get(Accept)
+	excluded := func(mt string) bool {
+		for _, item := range parseAcceptItems(header) {
+			if item.q <= 0 && matchMediaType(item.value, mt) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, item := range parseAccept(header) {
+		for _, n := range negotiable {
+			for _, mt := range n.mediaTypes {
+				if item.value == mt || matchMediaType(item.value, mt) && !excluded(mt) {
+					return n.render(rr, w, status, v)
+				}
+			}
+		}
+	}
+	return rr.JSON(w, status, v)
+}
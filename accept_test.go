@@ -0,0 +1,59 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_parseAccept(t *testing.T) {
+	items := parseAccept("application/json;q=0.8, application/xml;q=0.9, text/html, image/png;q=0")
+	expected := []string{"text/html", "application/xml", "application/json"}
+	if len(items) != len(expected) {
+		t.Fatalf("unexpected items: %v", items)
+	}
+	for i, item := range items {
+		if item.value != expected[i] {
+			t.Errorf("unexpected item at %d. got: %s want: %s", i, item.value, expected[i])
+		}
+	}
+}
+
+func Test_Negotiate(t *testing.T) {
+	r := New()
+	usr := user{"John Doe", 30}
+	jsonBody, _ := r.json(usr)
+	cases := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", ContentJSON, string(jsonBody)},
+		{"*/*", ContentJSON, string(jsonBody)},
+		{"image/png", ContentJSON, string(jsonBody)},
+		{"application/xml;q=0.9, application/json;q=0.8", ContentXML, defaultXMLPrefix + "<user><Name>John Doe</Name><Age>30</Age></user>"},
+		{"text/html, application/x-yaml", ContentYAML, "name: John Doe\nage: 30\n"},
+		{"application/json;q=0, text/*", ContentXML, defaultXMLPrefix + "<user><Name>John Doe</Name><Age>30</Age></user>"},
+		{"application/json;q=0, */*", ContentXML, defaultXMLPrefix + "<user><Name>John Doe</Name><Age>30</Age></user>"},
+		{"application/json;q=0, application/xml;q=0, text/xml;q=0, */*", ContentYAML, "name: John Doe\nage: 30\n"},
+		{"*/*;q=0, application/json", ContentJSON, string(jsonBody)},
+	}
+
+	for _, c := range cases {
+		var err error
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.Negotiate(w, req, http.StatusOK, usr)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/user", nil)
+		req.Header.Set(Accept, c.accept)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkContentType(t, res.Header().Get(ContentType), c.contentType+"; charset="+defaultCharSet)
+		checkBody(t, res.Body.String(), c.body)
+		checkBody(t, res.Header().Get(Vary), Accept)
+	}
+}
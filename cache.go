@@ -0,0 +1,220 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"container/list"
+	"html/template"
+	"sync"
+	"time"
+)
+
+// rawHTMLTags describes the elements whose content is kept as it is by minifyHTML
+var rawHTMLTags = []string{"pre", "textarea", "script", "style"}
+
+// minifyHTML collapse the runs of whitespace of html into a single space as the whitespace between inline elements
+// is significant, the tags with their quoted attribute values and the pre, textarea, script and style blocks are
+// kept as it is
+func minifyHTML(bs []byte) []byte {
+	out := make([]byte, 0, len(bs))
+	for i := 0; i < len(bs); {
+		switch c := bs[i]; {
+		case c == '<':
+			end := htmlTagEnd(bs, i)
+			out = append(out, bs[i:end]...)
+			if name := rawHTMLTag(bs[i:end]); name != "" {
+				closing := indexFoldASCII(bs[end:], "</"+name)
+				if closing < 0 {
+					closing = len(bs) - end
+				}
+				out = append(out, bs[end:end+closing]...)
+				end += closing
+			}
+			i = end
+		case isHTMLSpace(c):
+			for i < len(bs) && isHTMLSpace(bs[i]) {
+				i++
+			}
+			out = append(out, ' ')
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return bytes.TrimSpace(out)
+}
+
+// htmlTagEnd return the index after the > closing the tag starting at i, the > of the quoted attribute values
+// are skipped
+func htmlTagEnd(bs []byte, i int) int {
+	var quote byte
+	for j := i + 1; j < len(bs); j++ {
+		switch c := bs[j]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return j + 1
+		}
+	}
+	return len(bs)
+}
+
+// rawHTMLTag return the lower case name of the opening tag when it is one of rawHTMLTags
+func rawHTMLTag(tag []byte) string {
+	if len(tag) < 2 || tag[1] == '/' {
+		return ""
+	}
+	for _, name := range rawHTMLTags {
+		if len(tag) > len(name)+1 && bytes.EqualFold(tag[1:len(name)+1], []byte(name)) {
+			if c := tag[len(name)+1]; c == '>' || c == '/' || isHTMLSpace(c) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// indexFoldASCII return the index of the first ASCII case insensitive occurrence of sub in bs, -1 when missing
+func indexFoldASCII(bs []byte, sub string) int {
+	for i := 0; i+len(sub) <= len(bs); i++ {
+		if bytes.EqualFold(bs[i:i+len(sub)], []byte(sub)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isHTMLSpace report whether the byte is an HTML whitespace
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+type (
+	// htmlCacheEntry describes a cached rendered html
+	htmlCacheEntry struct {
+		body    []byte
+		expires time.Time
+		created time.Time
+	}
+
+	// htmlCache describes a size limited cache of rendered html
+	htmlCache struct {
+		mu         sync.Mutex
+		ttl        time.Duration
+		maxEntries int
+		entries    map[string]htmlCacheEntry
+	}
+)
+
+// newHTMLCache return a new html cache, zero ttl means the entries never expire
+func newHTMLCache(ttl time.Duration, maxEntries int) *htmlCache {
+	return &htmlCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]htmlCacheEntry),
+	}
+}
+
+// get return the cached body of the key if it exist and not expired
+func (c *htmlCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.body, true
+}
+
+// set store the body for the key, the oldest entry is evicted when the cache is full
+func (c *htmlCache) set(key string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictOldest()
+	}
+	now := time.Now()
+	e := htmlCacheEntry{body: body, created: now}
+	if c.ttl > 0 {
+		e.expires = now.Add(c.ttl)
+	}
+	c.entries[key] = e
+}
+
+// evictOldest remove the oldest entry from the cache
+func (c *htmlCache) evictOldest() {
+	var oldest string
+	var created time.Time
+	for k, e := range c.entries {
+		if oldest == "" || e.created.Before(created) {
+			oldest = k
+			created = e.created
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+type (
+	// templateCacheEntry describes a cached parsed template set
+	templateCacheEntry struct {
+		key string
+		t   *template.Template
+	}
+
+	// templateCache describes a least recently used cache of the template sets parsed by Template
+	templateCache struct {
+		mu      sync.Mutex
+		size    int
+		ll      *list.List
+		entries map[string]*list.Element
+	}
+)
+
+// newTemplateCache return a new template set cache holding up to size sets
+func newTemplateCache(size int) *templateCache {
+	return &templateCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get return the cached template set of the key and mark it as the most recently used
+func (c *templateCache) get(key string) (*template.Template, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*templateCacheEntry).t, true
+}
+
+// set store the template set for the key, the least recently used set is evicted when the cache is full
+func (c *templateCache) set(key string, t *template.Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*templateCacheEntry).t = t
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.ll.PushFront(&templateCacheEntry{key: key, t: t})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*templateCacheEntry).key)
+	}
+}
@@ -0,0 +1,172 @@
+package renderer
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_minifyHTML(t *testing.T) {
+	input := "<html>\n  <body>\n    <p>Hello   John</p>\n    <pre>  keep\n  this</pre>\n  </body>\n</html>\n"
+	expected := "<html> <body> <p>Hello John</p> <pre>  keep\n  this</pre> </body> </html>"
+	checkBody(t, string(minifyHTML([]byte(input))), expected)
+
+	// the whitespace between inline elements is kept
+	checkBody(t, string(minifyHTML([]byte("<b>a</b>\n   <i>b</i>"))), "<b>a</b> <i>b</i>")
+
+	// the attribute values, pre and textarea are kept as it is
+	input = "<div  title=\"a   b\" data-x='c > d'>\n  x  </div><PRE class=\"code\"> a\n   b</PRE>\n<textarea>  t  </textarea>"
+	expected = "<div  title=\"a   b\" data-x='c > d'> x </div><PRE class=\"code\"> a\n   b</PRE> <textarea>  t  </textarea>"
+	checkBody(t, string(minifyHTML([]byte(input))), expected)
+}
+
+// profile describes a template data whose title is read through a method
+type profile struct {
+	id    string
+	title string
+}
+
+func (p profile) Title() string { return p.title }
+
+// keyedProfile describes a profile cached by its id
+type keyedProfile struct {
+	profile
+}
+
+func (p keyedProfile) CacheKey() string { return p.id }
+
+func Test_HTML_cache_key(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/profile.tmpl", []byte(`{{define "profile"}}<h1>{{.Title}}</h1>{{end}}`), perm)
+	r := New(Options{ParseGlobPattern: dir + "/*.tmpl", CacheRenderedHTML: true})
+
+	render := func(v interface{}, opts ...RenderOption) string {
+		res := httptest.NewRecorder()
+		checkNil(t, r.HTML(res, http.StatusOK, "profile", v, opts...))
+		return res.Body.String()
+	}
+
+	// the data without a key is never cached
+	checkBody(t, render(profile{"1", "Alice"}), "<h1>Alice</h1>")
+	checkBody(t, render(profile{"2", "Bob"}), "<h1>Bob</h1>")
+
+	// the data is cached by its key
+	checkBody(t, render(keyedProfile{profile{"1", "Alice"}}), "<h1>Alice</h1>")
+	checkBody(t, render(keyedProfile{profile{"2", "Bob"}}), "<h1>Bob</h1>")
+	checkBody(t, render(keyedProfile{profile{"1", "Alice v2"}}), "<h1>Alice</h1>")
+
+	// the per call key takes precedence
+	checkBody(t, render(keyedProfile{profile{"1", "Alice v2"}}, WithCacheKey("1:v2")), "<h1>Alice v2</h1>")
+}
+
+func Test_htmlCache_eviction_and_ttl(t *testing.T) {
+	c := newHTMLCache(0, 2)
+	c.set("a", []byte("a"))
+	time.Sleep(time.Millisecond)
+	c.set("b", []byte("b"))
+	c.set("c", []byte("c"))
+	if _, ok := c.get("a"); ok {
+		t.Error("oldest entry should be evicted")
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Error("newest entry should be cached")
+	}
+
+	c = newHTMLCache(time.Nanosecond, 2)
+	c.set("a", []byte("a"))
+	time.Sleep(time.Millisecond)
+	if _, ok := c.get("a"); ok {
+		t.Error("expired entry should not be served")
+	}
+}
+
+func Test_HTML_cache_rendered(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	index := `{{define "homePage"}}<html>
+	<body>
+		<p>{{count}} {{.name}}</p>
+	</body>
+</html>{{end}}`
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
+
+	calls := 0
+	r := New(
+		Options{
+			ParseGlobPattern:  dir + "/*.tmpl",
+			CacheRenderedHTML: true,
+			CacheTTL:          time.Minute,
+			FuncMap: []template.FuncMap{
+				{"count": func() int { calls++; return calls }},
+			},
+		},
+	)
+
+	expected := `<html> <body> <p>1 john</p> </body> </html>`
+
+	for i := 0; i < 2; i++ {
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.HTML(w, http.StatusOK, "homePage", M{"name": "john"}, WithCacheKey("john"))
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/html", nil)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkBody(t, res.Body.String(), expected)
+	}
+
+	if calls != 1 {
+		t.Errorf("template should be executed once, executed %d times", calls)
+	}
+}
+
+func Test_Template_cache_lru(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template-cache")
+	checkNil(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a", "b", "c"} {
+		ioutil.WriteFile(dir+"/"+name+".tpl", []byte(`<p>`+name+` {{.}}</p>`), os.ModePerm)
+	}
+
+	r := New(Options{TemplateCacheSize: 2})
+	render := func(name string) {
+		res := httptest.NewRecorder()
+		err := r.Template(res, http.StatusOK, []string{dir + "/" + name + ".tpl"}, "John")
+		checkNil(t, err)
+		checkBody(t, res.Body.String(), `<p>`+name+` John</p>`)
+	}
+
+	render("a")
+	render("b")
+	render("a") // a becomes the most recently used
+	render("c") // b is evicted
+
+	for name, cached := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := r.templateSets.get(dir + "/" + name + ".tpl"); ok != cached {
+			t.Errorf("template set %s cached: %v want: %v", name, ok, cached)
+		}
+	}
+	if r.templateSets.ll.Len() != 2 {
+		t.Errorf("cache should hold 2 sets, got %d", r.templateSets.ll.Len())
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// AcceptEncoding describes the Accept-Encoding header
+	AcceptEncoding string = "Accept-Encoding"
+	// ContentEncoding describes the Content-Encoding header
+	ContentEncoding string = "Content-Encoding"
+	// ContentLength describes the Content-Length header
+	ContentLength string = "Content-Length"
+	// Vary describes the Vary header
+	Vary string = "Vary"
+
+	encodingGzip string = "gzip"
+	encodingZstd string = "zstd"
+
+	// zstdDictMagic is the magic number starting a dictionary trained by "zstd --train"
+	zstdDictMagic uint32 = 0xEC30A437
+)
+
+// acceptsEncoding report whether the request accepts the content coding, codings with q=0 are not accepted
+func acceptsEncoding(req *http.Request, coding string) bool {
+	if req == nil {
+		return false
+	}
+	for _, part := range strings.Split(req.Header.Get(AcceptEncoding), ",") {
+		params := strings.Split(part, ";")
+		name := strings.TrimSpace(params[0])
+		if name != coding && name != "*" {
+			continue
+		}
+		accepted := true
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				q, err := strconv.ParseFloat(p[2:], 64)
+				accepted = err == nil && q > 0
+			}
+		}
+		return accepted
+	}
+	return false
+}
+
+// Compressor describes an optional content coding e.g: zstd, the compression level is a concern of the implementation
+type Compressor interface {
+	// Encoding return the content coding name used in Accept-Encoding and Content-Encoding e.g: zstd
+	Encoding() string
+	// NewWriter return a writer compressing into w, dict is the CompressionDict option and may be nil
+	NewWriter(w io.Writer, dict []byte) (io.WriteCloser, error)
+}
+
+// gzipCompressor describes the built-in gzip coding enabled by EnableGzip
+type gzipCompressor struct{}
+
+// Encoding return the gzip content coding
+func (gzipCompressor) Encoding() string { return encodingGzip }
+
+// NewWriter return a gzip writer, gzip has no preset dictionary and ignores dict
+func (gzipCompressor) NewWriter(w io.Writer, dict []byte) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// ZstdCompressor describes the zstd content coding, add it to the Compressors option to send zstd to the
+// requests accepting it
+type ZstdCompressor struct {
+	// Level set the zstd compression level from 1 (fastest) to 22 (best); default 3
+	Level int
+	// DictID set the dictionary id written in the frames when CompressionDict is raw content, the client
+	// decompress with the same id and content. A dictionary trained by "zstd --train" carries its own id
+	DictID uint32
+}
+
+// Encoding return the zstd content coding
+func (ZstdCompressor) Encoding() string { return encodingZstd }
+
+// NewWriter return a zstd writer at the configured level using dict as the shared dictionary when not empty
+func (z ZstdCompressor) NewWriter(w io.Writer, dict []byte) (io.WriteCloser, error) {
+	level := z.Level
+	if level == 0 {
+		level = 3
+	}
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level))}
+	switch {
+	case len(dict) >= 4 && binary.LittleEndian.Uint32(dict) == zstdDictMagic:
+		opts = append(opts, zstd.WithEncoderDict(dict))
+	case len(dict) > 0:
+		opts = append(opts, zstd.WithEncoderDictRaw(z.DictID, dict))
+	}
+	return zstd.NewWriter(w, opts...)
+}
+
+// compressor return the first configured compressor accepted by the bound request, the Compressors option is
+// preferred over gzip. Nil is returned when the body must be sent as it is
+func (r *Render) compressor(w http.ResponseWriter, bs []byte) Compressor {
+	candidates := r.opts.Compressors
+	if r.opts.EnableGzip {
+		candidates = append(candidates[:len(candidates):len(candidates)], gzipCompressor{})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	w.Header().Add(Vary, AcceptEncoding)
+	if len(bs) < r.opts.GzipMinSize {
+		return nil
+	}
+	for _, c := range candidates {
+		if acceptsEncoding(r.req, c.Encoding()) {
+			return c
+		}
+	}
+	return nil
+}
+
+// writeCompressed write the status and the body compressed by the compressor
+func (r *Render) writeCompressed(w http.ResponseWriter, status int, bs []byte, c Compressor) error {
+	cw, err := c.NewWriter(w, r.opts.CompressionDict)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(ContentEncoding, c.Encoding())
+	w.Header().Del(ContentLength)
+	r.writeHeader(w, status)
+
+	if _, err = cw.Write(bs); err != nil {
+		cw.Close()
+		return err
+	}
+	return cw.Close()
+}
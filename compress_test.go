@@ -0,0 +1,245 @@
+package renderer
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func Test_acceptsEncoding(t *testing.T) {
+	cases := map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"deflate, gzip;q=1.0": true,
+		"gzip;q=0":            false,
+		"br, *":               true,
+		"br, deflate":         false,
+	}
+	for header, want := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set(AcceptEncoding, header)
+		if got := acceptsEncoding(req, "gzip"); got != want {
+			t.Errorf("accepts gzip for %q. got: %v want: %v", header, got, want)
+		}
+	}
+}
+
+func Test_gzip(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/home.tpl", []byte(`{{define "content"}}<h1>{{.Name}}</h1>{{end}}`), perm)
+	ioutil.WriteFile(dir+"/base.lout", []byte(`<html>{{template "content" .}}</html>`), perm)
+	ioutil.WriteFile(dir+"/index.html", []byte(`{{define "indexPage"}}<html><h1>{{.Name}}</h1></html>{{end}}`), perm)
+
+	r := New(Options{
+		TemplateDir:      dir,
+		ParseGlobPattern: dir + "/*.html",
+	}).Gzip(true)
+	usr := user{"John Doe", 30}
+
+	methods := map[string]func(rnd *Render, w http.ResponseWriter) error{
+		"JSON": func(rnd *Render, w http.ResponseWriter) error { return rnd.JSON(w, http.StatusOK, usr) },
+		"XML":  func(rnd *Render, w http.ResponseWriter) error { return rnd.XML(w, http.StatusOK, usr) },
+		"YAML": func(rnd *Render, w http.ResponseWriter) error { return rnd.YAML(w, http.StatusOK, usr) },
+		"HTML": func(rnd *Render, w http.ResponseWriter) error { return rnd.HTML(w, http.StatusOK, "indexPage", usr) },
+		"View": func(rnd *Render, w http.ResponseWriter) error { return rnd.View(w, http.StatusOK, "home", usr) },
+		"Template": func(rnd *Render, w http.ResponseWriter) error {
+			return rnd.Template(w, http.StatusOK, []string{dir + "/base.lout", dir + "/home.tpl"}, usr)
+		},
+	}
+
+	for name, render := range methods {
+		// the uncompressed form is rendered without a bound request
+		plain := httptest.NewRecorder()
+		checkNil(t, render(r, plain))
+		if plain.Header().Get(ContentEncoding) != "" {
+			t.Errorf("%s: unbound render should not be compressed", name)
+		}
+
+		for _, accept := range []string{"gzip", "br"} {
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set(AcceptEncoding, accept)
+			checkNil(t, render(r.WithRequest(req), res))
+			checkStatusOK(t, res.Code)
+			if res.Header().Get(ContentLength) != "" {
+				t.Errorf("%s: Content-Length should not be set", name)
+			}
+
+			if accept != "gzip" {
+				if res.Header().Get(ContentEncoding) != "" {
+					t.Errorf("%s: body should not be compressed when gzip is not accepted", name)
+				}
+				checkBody(t, res.Body.String(), plain.Body.String())
+				continue
+			}
+			if res.Header().Get(ContentEncoding) != "gzip" {
+				t.Fatalf("%s: expected gzip Content-Encoding", name)
+			}
+			gz, gerr := gzip.NewReader(res.Body)
+			checkNil(t, gerr)
+			body, _ := ioutil.ReadAll(gz)
+			checkBody(t, string(body), plain.Body.String())
+		}
+	}
+}
+
+func Test_JSON_gzip_min_size(t *testing.T) {
+	r := New(Options{
+		EnableGzip:  true,
+		GzipMinSize: 100,
+	})
+	var err error
+
+	small := M{"name": "John Doe"}
+	large := M{"name": strings.Repeat("John Doe ", 50)}
+
+	for _, v := range []M{small, large} {
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.WithRequest(req).JSON(w, http.StatusOK, v)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/json", nil)
+		req.Header.Set(AcceptEncoding, "gzip")
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		expected, _ := r.json(v)
+
+		if len(expected) < 100 {
+			if res.Header().Get(ContentEncoding) != "" {
+				t.Error("small body should not be compressed")
+			}
+			checkBody(t, res.Body.String(), string(expected))
+			continue
+		}
+
+		if res.Header().Get(ContentEncoding) != "gzip" {
+			t.Error("large body should be compressed")
+		}
+		gr, gerr := gzip.NewReader(res.Body)
+		checkNil(t, gerr)
+		bs, _ := ioutil.ReadAll(gr)
+		checkBody(t, string(bs), string(expected))
+	}
+}
+
+func Test_JSON_gzip_compression_dict(t *testing.T) {
+	r := New(Options{
+		EnableGzip:      true,
+		CompressionDict: []byte(`{"id":,"name":"","email":""}`),
+	})
+	var err error
+	v := M{"id": 1, "name": "John Doe", "email": "john@example.com"}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.WithRequest(req).JSON(w, http.StatusOK, v)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/json", nil)
+	req.Header.Set(AcceptEncoding, "gzip")
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	if res.Header().Get(ContentEncoding) != "gzip" {
+		t.Fatal("expected gzip Content-Encoding")
+	}
+	gz, err := gzip.NewReader(res.Body)
+	checkNil(t, err)
+	body, _ := ioutil.ReadAll(gz)
+	expected, _ := r.json(v)
+	checkBody(t, string(body), string(expected))
+}
+
+func Test_JSON_zstd_compressor(t *testing.T) {
+	r := New(Options{
+		EnableGzip:  true,
+		Compressors: []Compressor{ZstdCompressor{Level: 19}},
+	})
+	var err error
+	v := M{"name": strings.Repeat("John Doe ", 50)}
+	expected, _ := r.json(v)
+
+	for accept, encoding := range map[string]string{"gzip, zstd": "zstd", "gzip": "gzip", "br": ""} {
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.WithRequest(req).JSON(w, http.StatusOK, v)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/json", nil)
+		req.Header.Set(AcceptEncoding, accept)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		if got := res.Header().Get(ContentEncoding); got != encoding {
+			t.Errorf("Content-Encoding for %q. got: %q want: %q", accept, got, encoding)
+		}
+		if encoding != "zstd" {
+			continue
+		}
+		zr, err := zstd.NewReader(res.Body)
+		checkNil(t, err)
+		body, _ := ioutil.ReadAll(zr)
+		zr.Close()
+		checkBody(t, string(body), string(expected))
+	}
+}
+
+func Test_JSON_zstd_compression_dict(t *testing.T) {
+	dict := []byte(`{"email":"@example.com","id":,"name":""}`)
+	r := New(Options{
+		Compressors:     []Compressor{ZstdCompressor{DictID: 7}},
+		CompressionDict: dict,
+	})
+	var err error
+	v := M{"id": 1, "name": "John Doe", "email": "john@example.com"}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.WithRequest(req).JSON(w, http.StatusOK, v)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/json", nil)
+	req.Header.Set(AcceptEncoding, "zstd")
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	if res.Header().Get(ContentEncoding) != "zstd" {
+		t.Fatal("expected zstd Content-Encoding")
+	}
+	compressed := res.Body.Bytes()
+
+	// the frame can not be decoded without the dictionary
+	zr, err := zstd.NewReader(nil)
+	checkNil(t, err)
+	if _, err = zr.DecodeAll(compressed, nil); err == nil {
+		t.Error("expected the frame to require the dictionary")
+	}
+	zr.Close()
+
+	zr, err = zstd.NewReader(nil, zstd.WithDecoderDictRaw(7, dict))
+	checkNil(t, err)
+	defer zr.Close()
+	body, err := zr.DecodeAll(compressed, nil)
+	checkNil(t, err)
+	expected, _ := r.json(v)
+	checkBody(t, string(body), string(expected))
+}
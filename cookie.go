@@ -0,0 +1,31 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import "net/http"
+
+// setCookie set a copy of the cookie with the CookieSecure, CookieSameSite and CookieDomain options applied, the
+// attributes already set on the cookie are kept and the cookie of the caller is not changed
+func (r *Render) setCookie(w http.ResponseWriter, c *http.Cookie) {
+	c2 := *c
+	if r.opts.CookieSecure {
+		c2.Secure = true
+	}
+	if c2.SameSite == 0 {
+		c2.SameSite = r.opts.CookieSameSite
+	}
+	if c2.Domain == "" {
+		c2.Domain = r.opts.CookieDomain
+	}
+	http.SetCookie(w, &c2)
+}
+
+// WithCookies return a copy of the Render which sets the cookies before writing the status of the response it renders,
+// the CookieSecure, CookieSameSite and CookieDomain options are applied e.g: rnd.WithCookies(cookie).JSON(w, 200, v)
+func (r *Render) WithCookies(cookies ...*http.Cookie) *Render {
+	c := *r
+	c.cookies = append(r.cookies[:len(r.cookies):len(r.cookies)], cookies...)
+	return &c
+}
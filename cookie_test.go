@@ -0,0 +1,42 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_WithCookies(t *testing.T) {
+	r := New(Options{CookieSecure: true, CookieDomain: "example.com"})
+	session := &http.Cookie{Name: "session", Value: "abc"}
+	res := httptest.NewRecorder()
+	err := r.WithCookies(
+		session,
+		&http.Cookie{Name: "theme", Value: "dark", SameSite: http.SameSiteStrictMode},
+	).JSON(res, http.StatusOK, M{"ok": true})
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `{"ok":true}`)
+
+	cookies := res.Result().Cookies()
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 cookies, got %d", len(cookies))
+	}
+	if cookies[0].Name != "session" || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Errorf("unexpected cookie: %v", cookies[0])
+	}
+	if cookies[1].Name != "theme" || cookies[1].SameSite != http.SameSiteStrictMode {
+		t.Errorf("unexpected cookie: %v", cookies[1])
+	}
+	// the defaults are applied to a copy of the cookie of the caller
+	if session.Secure || session.SameSite != 0 || session.Domain != "" {
+		t.Errorf("cookie of the caller should not be changed: %v", session)
+	}
+
+	// the cookies are bound to the copy only
+	res = httptest.NewRecorder()
+	checkNil(t, r.JSON(res, http.StatusOK, M{"ok": true}))
+	if len(res.Result().Cookies()) != 0 {
+		t.Error("cookies should not be set by the shared renderer")
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	jsonMarshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// canonicalJSON re-encode the JSON with the keys of every object sorted, the numbers are kept as it is. It is used
+// for the output of json.Marshaler which encoding/json does not sort
+func canonicalJSON(bs []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonNormalizer describes how the time.Time values and the json.Marshaler outputs are rewritten while the data is
+// encoded by encoding/json
+type jsonNormalizer struct {
+	layout    string
+	utc       bool
+	canonical bool
+	walk      map[reflect.Type]bool
+}
+
+// canonicalMarshaler describes a json.Marshaler whose output objects are encoded with sorted keys
+type canonicalMarshaler struct {
+	m json.Marshaler
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (c canonicalMarshaler) MarshalJSON() ([]byte, error) {
+	bs, err := c.m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(bs)
+}
+
+// normalizeJSON return the data to encode with encoding/json where the time.Time values are formatted using the
+// layout, in UTC when utc is set, and the json.Marshaler outputs are sorted when canonical is set. The values
+// without a time or a json.Marshaler are kept as it is so the json tags and options apply as usual; a layout
+// of "" keeps the times as it is
+func normalizeJSON(v interface{}, layout string, utc, canonical bool) interface{} {
+	n := &jsonNormalizer{layout: layout, utc: utc, canonical: canonical, walk: make(map[reflect.Type]bool)}
+	return n.value(reflect.ValueOf(v))
+}
+
+// needsWalk report whether the values of the type may contain a value the normalizer rewrites
+func (n *jsonNormalizer) needsWalk(t reflect.Type) bool {
+	if walk, ok := n.walk[t]; ok {
+		return walk
+	}
+	// a recursive type is assumed to need no walk until its fields tell otherwise
+	n.walk[t] = false
+	walk := false
+	switch {
+	case t == timeType:
+		walk = n.layout != ""
+	case t.Implements(jsonMarshalerType):
+		walk = n.canonical
+	case t.Implements(textMarshalerType):
+	default:
+		switch t.Kind() {
+		case reflect.Interface:
+			walk = true
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			walk = n.needsWalk(t.Elem())
+		case reflect.Map:
+			walk = n.needsWalk(t.Elem())
+		case reflect.Struct:
+			for i := 0; i < t.NumField() && !walk; i++ {
+				if f := t.Field(i); f.PkgPath == "" || f.Anonymous {
+					walk = n.needsWalk(f.Type)
+				}
+			}
+		}
+	}
+	n.walk[t] = walk
+	return walk
+}
+
+// value return the normalized value, the values which need no walk are returned as it is
+func (n *jsonNormalizer) value(rv reflect.Value) interface{} {
+	if !rv.IsValid() {
+		return nil
+	}
+	if !n.needsWalk(rv.Type()) {
+		return rv.Interface()
+	}
+	if rv.Type() == timeType {
+		t := rv.Interface().(time.Time)
+		if n.utc {
+			t = t.UTC()
+		}
+		return t.Format(n.layout)
+	}
+	if rv.Type().Implements(jsonMarshalerType) {
+		if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil() {
+			return nil
+		}
+		return canonicalMarshaler{rv.Interface().(json.Marshaler)}
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return n.value(rv.Elem())
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		// the key type is kept so the keys are encoded and sorted as usual
+		m := reflect.MakeMapWithSize(reflect.MapOf(rv.Type().Key(), emptyInterfaceType), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			if val := n.value(iter.Value()); val != nil {
+				m.SetMapIndex(iter.Key(), reflect.ValueOf(val))
+			} else {
+				m.SetMapIndex(iter.Key(), reflect.Zero(emptyInterfaceType))
+			}
+		}
+		return m.Interface()
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			s[i] = n.value(rv.Index(i))
+		}
+		return s
+	case reflect.Struct:
+		return n.structValue(rv)
+	}
+	return rv.Interface()
+}
+
+// jsonStructField describes a field of the struct rebuilt by structValue
+type jsonStructField struct {
+	name   string
+	opts   string
+	depth  int
+	tagged bool
+	value  reflect.Value
+}
+
+// structValue rebuild the struct with the fields encoded by encoding/json in declaration order, the fields
+// promoted from the embedded structs follow the encoding/json precedence. The struct is returned as it is when
+// a field can not be read e.g: promoted from an unexported embedded struct
+func (n *jsonNormalizer) structValue(rv reflect.Value) interface{} {
+	var fields []jsonStructField
+	if !n.structFields(rv, 0, &fields) {
+		return rv.Interface()
+	}
+
+	// the shallowest field wins, then the tagged one, the other conflicting fields are dropped
+	byName := make(map[string][]int)
+	for i, f := range fields {
+		byName[f.name] = append(byName[f.name], i)
+	}
+	sfs := make([]reflect.StructField, 0, len(fields))
+	values := make([]reflect.Value, 0, len(fields))
+	for i, f := range fields {
+		if dominantField(fields, byName[f.name]) != i {
+			continue
+		}
+		tag := f.name
+		if f.opts != "" {
+			tag += "," + f.opts
+		}
+		ft := f.value.Type()
+		val := f.value
+		if n.needsWalk(ft) {
+			ft = emptyInterfaceType
+			if strings.Contains(f.opts, "omitempty") && isEmptyValue(f.value) {
+				val = reflect.Zero(ft)
+			} else if nv := n.value(f.value); nv != nil {
+				val = reflect.ValueOf(nv)
+			} else {
+				val = reflect.Zero(ft)
+			}
+		}
+		sfs = append(sfs, reflect.StructField{
+			Name: "F" + strconv.Itoa(len(sfs)),
+			Type: ft,
+			Tag:  reflect.StructTag(`json:"` + tag + `"`),
+		})
+		values = append(values, val)
+	}
+
+	out := reflect.New(reflect.StructOf(sfs)).Elem()
+	for i, val := range values {
+		out.Field(i).Set(val)
+	}
+	return out.Interface()
+}
+
+// structFields collect the fields of the struct encoded by encoding/json, false is returned when a field can not be read
+func (n *jsonNormalizer) structFields(rv reflect.Value, depth int, fields *[]jsonStructField) bool {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				if !n.structFields(fv, depth+1, fields) {
+					return false
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if !fv.CanInterface() {
+			return false
+		}
+		tagged := name != ""
+		if name == "" {
+			name = f.Name
+		}
+		*fields = append(*fields, jsonStructField{name: name, opts: opts, depth: depth, tagged: tagged, value: fv})
+	}
+	return true
+}
+
+// dominantField return the index of the field encoded for the name, -1 when the conflicting fields cancel out
+func dominantField(fields []jsonStructField, idx []int) int {
+	best := []int{idx[0]}
+	for _, i := range idx[1:] {
+		switch {
+		case fields[i].depth < fields[best[0]].depth:
+			best = []int{i}
+		case fields[i].depth == fields[best[0]].depth:
+			best = append(best, i)
+		}
+	}
+	if len(best) == 1 {
+		return best[0]
+	}
+	tagged := -1
+	for _, i := range best {
+		if fields[i].tagged {
+			if tagged >= 0 {
+				return -1
+			}
+			tagged = i
+		}
+	}
+	return tagged
+}
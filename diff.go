@@ -0,0 +1,140 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+const (
+	// DiffAdded describes a field present only in the new data
+	DiffAdded string = "added"
+	// DiffRemoved describes a field present only in the old data
+	DiffRemoved string = "removed"
+	// DiffChanged describes a field having different values
+	DiffChanged string = "changed"
+)
+
+type (
+	// DiffChange describes a field level change between two data, the field is the dotted JSON path e.g: address.city
+	DiffChange struct {
+		Field string
+		Kind  string
+		Old   interface{}
+		New   interface{}
+	}
+
+	// DiffFunc describes a function computing the field level changes between two data
+	DiffFunc func(old, new interface{}) ([]DiffChange, error)
+)
+
+// diffTemplate describes the HTML table of JSONDiffHTML
+var diffTemplate = template.Must(template.New("diff").Funcs(template.FuncMap{"json": diffValue}).Parse(
+	`<table class="json-diff"><thead><tr><th>Field</th><th>Old</th><th>New</th></tr></thead><tbody>` +
+		`{{range .}}<tr class="{{.Kind}}"><td>{{.Field}}</td>` +
+		`<td>{{if ne .Kind "added"}}{{json .Old}}{{end}}</td>` +
+		`<td>{{if ne .Kind "removed"}}{{json .New}}{{end}}</td></tr>{{end}}` +
+		`</tbody></table>`))
+
+// diffValue return the JSON text of the value
+func diffValue(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(bs)
+}
+
+// JSONDiff return the changes between the JSON representation of the two data sorted by field, the objects are
+// compared field by field and the other values are compared as a whole
+func JSONDiff(old, new interface{}) ([]DiffChange, error) {
+	o, err := jsonValue(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := jsonValue(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []DiffChange
+	diffJSONValues(&changes, "", o, n)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// jsonValue return the generic JSON representation of the data
+func jsonValue(v interface{}) (interface{}, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(bs, &out)
+	return out, err
+}
+
+// diffJSONValues append the changes between the two JSON values at the field
+func diffJSONValues(changes *[]DiffChange, field string, old, new interface{}) {
+	om, oldIsObject := old.(map[string]interface{})
+	nm, newIsObject := new.(map[string]interface{})
+	if !oldIsObject || !newIsObject {
+		if !reflect.DeepEqual(old, new) {
+			*changes = append(*changes, DiffChange{Field: field, Kind: DiffChanged, Old: old, New: new})
+		}
+		return
+	}
+	for k, ov := range om {
+		f := joinField(field, k)
+		nv, ok := nm[k]
+		if !ok {
+			*changes = append(*changes, DiffChange{Field: f, Kind: DiffRemoved, Old: ov})
+			continue
+		}
+		diffJSONValues(changes, f, ov, nv)
+	}
+	for k, nv := range nm {
+		if _, ok := om[k]; !ok {
+			*changes = append(*changes, DiffChange{Field: joinField(field, k), Kind: DiffAdded, New: nv})
+		}
+	}
+}
+
+// joinField join the field path and the key
+func joinField(field, key string) string {
+	if field == "" {
+		return key
+	}
+	if key == "" {
+		return field + "." + strconv.Quote(key)
+	}
+	return field + "." + key
+}
+
+// JSONDiffHTML render the field level diff of the old and new data as an HTML table, the rows are classed
+// by the kind of the change (added, removed or changed) so that they can be highlighted. The DiffFunc option
+// compute the changes; default: JSONDiff
+func (r *Render) JSONDiffHTML(w http.ResponseWriter, status int, old, new interface{}) error {
+	diff := r.opts.DiffFunc
+	if diff == nil {
+		diff = JSONDiff
+	}
+	changes, err := diff(old, new)
+	if err != nil {
+		return err
+	}
+	buf := r.newBuffer()
+	if err = diffTemplate.Execute(buf, changes); err != nil {
+		return err
+	}
+	r.setHTMLHeaders(w)
+	return r.writeBody(w, status, buf.Bytes())
+}
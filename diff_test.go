@@ -0,0 +1,79 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_JSONDiff(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	type account struct {
+		Name    string  `json:"name"`
+		Age     int     `json:"age"`
+		Email   string  `json:"email,omitempty"`
+		Address address `json:"address"`
+	}
+	old := account{Name: "John Doe", Age: 30, Email: "john@example.com", Address: address{City: "Dhaka"}}
+	new := account{Name: "John Doe", Age: 31, Address: address{City: "Sylhet", Zip: "3100"}}
+
+	changes, err := JSONDiff(old, new)
+	checkNil(t, err)
+	expected := []DiffChange{
+		{Field: "address.city", Kind: DiffChanged, Old: "Dhaka", New: "Sylhet"},
+		{Field: "address.zip", Kind: DiffAdded, New: "3100"},
+		{Field: "age", Kind: DiffChanged, Old: 30.0, New: 31.0},
+		{Field: "email", Kind: DiffRemoved, Old: "john@example.com"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	for i, c := range changes {
+		if c != expected[i] {
+			t.Errorf("unexpected change at %d. got: %+v want: %+v", i, c, expected[i])
+		}
+	}
+}
+
+func Test_JSONDiffHTML(t *testing.T) {
+	r := New()
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONDiffHTML(w, http.StatusOK, M{"name": "John", "role": "<user>"}, M{"name": "John", "role": "admin"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/audit", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	row := `<tr class="changed"><td>role</td><td>&#34;\u003cuser\u003e&#34;</td><td>&#34;admin&#34;</td></tr>`
+	if !strings.Contains(res.Body.String(), row) {
+		t.Errorf("expected the changed row %s in: %s", row, res.Body.String())
+	}
+	if strings.Contains(res.Body.String(), "<td>name</td>") {
+		t.Error("unchanged field should not be rendered")
+	}
+}
+
+func Test_JSONDiffHTML_DiffFunc(t *testing.T) {
+	r := New(Options{
+		DiffFunc: func(old, new interface{}) ([]DiffChange, error) {
+			return []DiffChange{{Field: "custom", Kind: DiffAdded, New: 1}}, nil
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := r.JSONDiffHTML(res, http.StatusOK, nil, nil)
+	checkNil(t, err)
+	if !strings.Contains(res.Body.String(), `<tr class="added"><td>custom</td><td></td><td>1</td></tr>`) {
+		t.Errorf("unexpected body: %s", res.Body.String())
+	}
+}
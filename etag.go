@@ -0,0 +1,71 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ETag describes the ETag header
+	ETag string = "ETag"
+	// IfNoneMatch describes the If-None-Match header
+	IfNoneMatch string = "If-None-Match"
+)
+
+// ETagFunc describes a function deriving the ETag value of the data e.g: from its version or UpdatedAt field
+type ETagFunc func(v interface{}) string
+
+// etagOf return the strong ETag of the body, the ETag of an encoded representation is suffixed with
+// the encoding so caches never serve a compressed body to clients which do not accept it
+func etagOf(bs []byte, encoding string) string {
+	sum := sha256.Sum256(bs)
+	tag := hex.EncodeToString(sum[:])
+	if encoding != "" {
+		tag += "-" + encoding
+	}
+	return `"` + tag + `"`
+}
+
+// etagMatch report whether the If-None-Match header matches the ETag using the weak comparison
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified report whether the bound GET or HEAD request already has the representation of the ETag
+func (r *Render) notModified(etag string) bool {
+	if r.req == nil || (r.req.Method != http.MethodGet && r.req.Method != http.MethodHead) {
+		return false
+	}
+	return etagMatch(r.req.Header.Get(IfNoneMatch), etag)
+}
+
+// JSONVersioned serve data as JSON with the weak ETag derived from the data by etagFunc instead of hashing the body,
+// 304 Not Modified is sent without a body when the If-None-Match header of the request matches the ETag
+func (r *Render) JSONVersioned(w http.ResponseWriter, req *http.Request, status int, v interface{}, etagFunc ETagFunc) error {
+	rr := r.WithRequest(req)
+	rr.opts.EnableETag = false
+
+	tag := `W/"` + strings.Replace(etagFunc(v), `"`, "", -1) + `"`
+	w.Header().Set(ETag, tag)
+	if rr.notModified(tag) {
+		rr.writeHeader(w, http.StatusNotModified)
+		return nil
+	}
+	return rr.JSON(w, status, v)
+}
@@ -0,0 +1,166 @@
+package renderer
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_JSON_gzip_etag(t *testing.T) {
+	r := New(Options{
+		EnableGzip: true,
+		EnableETag: true,
+	})
+	usr := user{"John Doe", 30}
+	expected := `{"Name":"John Doe","Age":30}`
+	var etags []string
+
+	for _, encoding := range []string{"", "gzip"} {
+		var err error
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.WithRequest(req).JSON(w, http.StatusOK, usr)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/json", nil)
+		req.Header.Set(AcceptEncoding, encoding)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		if res.Header().Get(Vary) != AcceptEncoding {
+			t.Error("response should vary by Accept-Encoding")
+		}
+		etag := res.Header().Get(ETag)
+		etags = append(etags, etag)
+
+		if encoding == "" {
+			if res.Header().Get(ContentEncoding) != "" {
+				t.Error("body should not be compressed")
+			}
+			if etag != etagOf([]byte(expected), "") {
+				t.Errorf("etag should represent the uncompressed body. got: %s", etag)
+			}
+			checkBody(t, res.Body.String(), expected)
+			continue
+		}
+
+		if !strings.HasSuffix(etag, `-gzip"`) {
+			t.Errorf("etag of the compressed body should be marked with the encoding. got: %s", etag)
+		}
+		gr, gerr := gzip.NewReader(res.Body)
+		checkNil(t, gerr)
+		bs, _ := ioutil.ReadAll(gr)
+		checkBody(t, string(bs), expected)
+	}
+
+	if etags[0] == etags[1] {
+		t.Error("etag of the compressed and uncompressed body should differ")
+	}
+}
+
+func Test_ETag_conditional(t *testing.T) {
+	tpl, err := ioutil.TempFile("", "etag-*.tpl")
+	checkNil(t, err)
+	defer os.Remove(tpl.Name())
+	tpl.WriteString(`<h1>{{.Name}}</h1>`)
+	tpl.Close()
+
+	r := New(Options{
+		EnableETag: true,
+	})
+	render := map[string]func(r *Render, w http.ResponseWriter) error{
+		"json": func(r *Render, w http.ResponseWriter) error {
+			return r.JSON(w, http.StatusOK, user{"John Doe", 30})
+		},
+		"xml": func(r *Render, w http.ResponseWriter) error {
+			return r.XML(w, http.StatusOK, user{"John Doe", 30})
+		},
+		"html": func(r *Render, w http.ResponseWriter) error {
+			return r.Template(w, http.StatusOK, []string{tpl.Name()}, user{"John Doe", 30})
+		},
+	}
+
+	for name, fn := range render {
+		// cache miss
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+name, nil)
+		req.Header.Set(IfNoneMatch, `"stale"`)
+		checkNil(t, fn(r.WithRequest(req), res))
+		checkStatusOK(t, res.Code)
+		etag := res.Header().Get(ETag)
+		if etag == "" || res.Body.Len() == 0 {
+			t.Errorf("%s: cache miss should be served with the ETag and the body", name)
+		}
+
+		// cache hit
+		res = httptest.NewRecorder()
+		req.Header.Set(IfNoneMatch, etag)
+		checkNil(t, fn(r.WithRequest(req), res))
+		if res.Code != http.StatusNotModified {
+			t.Errorf("%s: expected status %d, got %d", name, http.StatusNotModified, res.Code)
+		}
+		checkBody(t, res.Header().Get(ETag), etag)
+		checkBody(t, res.Body.String(), "")
+	}
+}
+
+func Test_etagMatch(t *testing.T) {
+	cases := map[string]bool{
+		"":                  false,
+		"*":                 true,
+		`"v1"`:              true,
+		`W/"v1"`:            true,
+		`"v0", W/"v1"`:      true,
+		`"v2"`:              false,
+		`W/"v10", "v1-old"`: false,
+	}
+	for header, want := range cases {
+		if got := etagMatch(header, `W/"v1"`); got != want {
+			t.Errorf("etagMatch(%q). got: %v want: %v", header, got, want)
+		}
+	}
+}
+
+func Test_JSONVersioned(t *testing.T) {
+	type article struct {
+		Title   string
+		Version int
+	}
+	r := New(Options{
+		EnableETag: true,
+	})
+	v := article{"Hello", 3}
+	etagFunc := func(v interface{}) string {
+		return fmt.Sprintf("v%d", v.(article).Version)
+	}
+
+	for _, ifNoneMatch := range []string{"", `W/"v2"`, `W/"v3"`} {
+		var err error
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.JSONVersioned(w, req, http.StatusOK, v, etagFunc)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/article", nil)
+		req.Header.Set(IfNoneMatch, ifNoneMatch)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkBody(t, res.Header().Get(ETag), `W/"v3"`)
+		if ifNoneMatch == `W/"v3"` {
+			if res.Code != http.StatusNotModified {
+				t.Errorf("expected status: %d, got: %d", http.StatusNotModified, res.Code)
+			}
+			checkBody(t, res.Body.String(), "")
+			continue
+		}
+		checkStatusOK(t, res.Code)
+		checkBody(t, res.Body.String(), `{"Title":"Hello","Version":3}`)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Location describes the Location header
+	Location string = "Location"
+
+	defaultFlashMaxAge = time.Minute
+)
+
+// checkRedirectStatus return an error when the status is not a redirection (3xx) status
+func checkRedirectStatus(status int) error {
+	if status < 300 || status > 399 {
+		return fmt.Errorf("renderer: invalid redirect status %d", status)
+	}
+	return nil
+}
+
+// Redirect redirect the request to the url, the status must be a redirection (3xx) status; 0 means 302 Found
+func (r *Render) Redirect(w http.ResponseWriter, req *http.Request, url string, status int) error {
+	if status == 0 {
+		status = http.StatusFound
+	}
+	if err := checkRedirectStatus(status); err != nil {
+		return err
+	}
+	r.WithRequest(req).setCommonHeaders(w)
+	http.Redirect(w, req, url, status)
+	return nil
+}
+
+// signFlash return the signature of the flash message bound to the cookie name
+func (r *Render) signFlash(key, msg string) string {
+	mac := hmac.New(sha256.New, r.opts.FlashSecret)
+	mac.Write([]byte(key + "=" + msg))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RedirectWithFlash set a short lived signed cookie named flashKey holding the flashMsg and redirect to the url,
+// the status must be a redirection (3xx) status e.g: http.StatusSeeOther. The message can be read by Flash
+func (r *Render) RedirectWithFlash(w http.ResponseWriter, req *http.Request, status int, url, flashKey, flashMsg string) error {
+	if len(r.opts.FlashSecret) == 0 {
+		return errors.New("renderer: FlashSecret option is required to sign the flash cookie")
+	}
+	if err := checkRedirectStatus(status); err != nil {
+		return err
+	}
+	r.setCookie(w, &http.Cookie{
+		Name:     flashKey,
+		Value:    base64.RawURLEncoding.EncodeToString([]byte(flashMsg)) + "." + r.signFlash(flashKey, flashMsg),
+		Path:     "/",
+		MaxAge:   int(r.opts.FlashMaxAge / time.Second),
+		HttpOnly: true,
+	})
+	w.Header().Set(Location, url)
+	r.WithRequest(req).writeHeader(w, status)
+	return nil
+}
+
+// Flash return the flash message of the signed cookie named flashKey and clear the cookie,
+// a missing or tampered cookie returns false
+func (r *Render) Flash(w http.ResponseWriter, req *http.Request, flashKey string) (string, bool) {
+	c, err := req.Cookie(flashKey)
+	if err != nil || len(r.opts.FlashSecret) == 0 {
+		return "", false
+	}
+	r.setCookie(w, &http.Cookie{Name: flashKey, Path: "/", MaxAge: -1, HttpOnly: true})
+	parts := strings.SplitN(c.Value, ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	msg, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(r.signFlash(flashKey, string(msg)))) {
+		return "", false
+	}
+	return string(msg), true
+}
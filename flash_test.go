@@ -0,0 +1,113 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RedirectWithFlash(t *testing.T) {
+	r := New(Options{
+		FlashSecret: []byte("secret"),
+	})
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.RedirectWithFlash(w, req, http.StatusSeeOther, "/users", "flash", "User created")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	if res.Code != http.StatusSeeOther {
+		t.Errorf("expected status: %d, got: %d", http.StatusSeeOther, res.Code)
+	}
+	if loc := res.Header().Get(Location); loc != "/users" {
+		t.Errorf("expected Location: /users, got: %s", loc)
+	}
+	cookie := res.Header().Get("Set-Cookie")
+	if !strings.HasPrefix(cookie, "flash=") || !strings.Contains(cookie, "Max-Age=60") || !strings.Contains(cookie, "HttpOnly") {
+		t.Errorf("unexpected Set-Cookie: %s", cookie)
+	}
+
+	// the flash is read back on the redirected request
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Set("Cookie", strings.SplitN(cookie, ";", 2)[0])
+	msg, ok := r.Flash(httptest.NewRecorder(), req, "flash")
+	if !ok || msg != "User created" {
+		t.Errorf("expected flash: User created, got: %q %v", msg, ok)
+	}
+
+	// a tampered cookie is rejected
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.AddCookie(&http.Cookie{Name: "flash", Value: "SGFja2Vk." + r.signFlash("flash", "User created")})
+	_, ok = r.Flash(httptest.NewRecorder(), req, "flash")
+	if ok {
+		t.Error("expected tampered flash to be rejected")
+	}
+}
+
+func Test_RedirectWithFlash_without_secret(t *testing.T) {
+	r := New()
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users", nil)
+	err := r.RedirectWithFlash(res, req, http.StatusSeeOther, "/users", "flash", "User created")
+	checkNotNil(t, err)
+}
+
+func Test_RedirectWithFlash_cookie_attributes(t *testing.T) {
+	r := New(Options{
+		FlashSecret:    []byte("secret"),
+		CookieSecure:   true,
+		CookieSameSite: http.SameSiteStrictMode,
+		CookieDomain:   "example.com",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users", nil)
+	err := r.RedirectWithFlash(res, req, http.StatusSeeOther, "/users", "flash", "User created")
+	checkNil(t, err)
+
+	cookie := res.Header().Get("Set-Cookie")
+	for _, attr := range []string{"Domain=example.com", "Secure", "SameSite=Strict", "HttpOnly"} {
+		if !strings.Contains(cookie, attr) {
+			t.Errorf("expected %s in Set-Cookie: %s", attr, cookie)
+		}
+	}
+}
+
+func Test_Redirect(t *testing.T) {
+	r := New()
+	cases := map[int]int{
+		http.StatusMovedPermanently: http.StatusMovedPermanently,
+		0:                           http.StatusFound,
+	}
+
+	for status, expected := range cases {
+		var err error
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.Redirect(w, req, "/login", status)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		if res.Code != expected {
+			t.Errorf("expected status: %d, got: %d", expected, res.Code)
+		}
+		checkBody(t, res.Header().Get(Location), "/login")
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin", nil)
+	err := r.Redirect(res, req, "/login", http.StatusOK)
+	checkNotNil(t, err)
+	if res.Header().Get(Location) != "" {
+		t.Error("invalid redirect should not set Location")
+	}
+}
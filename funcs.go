@@ -0,0 +1,139 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// baseFuncs return the template functions registered by default, include is bound to the executed template set by bindInclude
+func baseFuncs() template.FuncMap {
+	return template.FuncMap{
+		"dict":    dict,
+		"dataURI": dataURI,
+		"include": func(name string, data interface{}) (template.HTML, error) {
+			return "", errors.New("renderer: include is not bound to a template set")
+		},
+	}
+}
+
+// DefaultFuncs return the helper functions merged into the templates unless DisableDefaultFuncs is set,
+// the FuncMap option overrides the helpers of the same name e.g: {{upper .Name}} {{formatTime "2006-01-02" .CreatedAt}}
+func DefaultFuncs() template.FuncMap {
+	return template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"formatTime": formatTime,
+		"safeHTML":   safeHTML,
+		"default":    defaultValue,
+	}
+}
+
+// templateFuncs return the functions every template set is parsed with, before the FuncMap option
+func (r *Render) templateFuncs() template.FuncMap {
+	fm := baseFuncs()
+	if !r.opts.DisableDefaultFuncs {
+		for k, f := range DefaultFuncs() {
+			fm[k] = f
+		}
+	}
+	return fm
+}
+
+// title upper case the first letter of every word
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(c rune) rune {
+		defer func() { prev = c }()
+		if unicode.IsSpace(prev) {
+			return unicode.ToTitle(c)
+		}
+		return c
+	}, s)
+}
+
+// formatTime format the time with the layout e.g: {{formatTime "2006-01-02" .CreatedAt}}, the zero time is formatted as empty
+func formatTime(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// safeHTML mark the trusted string as html so it is not escaped
+func safeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
+
+// defaultValue return the value or def when the value is empty e.g: {{default "anonymous" .Name}}
+func defaultValue(def, v interface{}) interface{} {
+	if v == nil || isEmptyValue(reflect.ValueOf(v)) {
+		return def
+	}
+	return v
+}
+
+// dict build a map from the key value pairs e.g: {{include "widget" (dict "title" "Hi")}}
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("renderer: dict requires key value pairs")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		k, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("renderer: dict key must be a string, got %T", pairs[i])
+		}
+		m[k] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// bindInclude bind the include function to the template set so templates can execute a named template with custom data,
+// an include function provided by the FuncMap option is kept
+func (r *Render) bindInclude(t *template.Template) *template.Template {
+	for _, fm := range r.opts.FuncMap {
+		if _, ok := fm["include"]; ok {
+			return t
+		}
+	}
+	return t.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (template.HTML, error) {
+			buf := new(bytes.Buffer)
+			if err := t.ExecuteTemplate(buf, name, data); err != nil {
+				return "", err
+			}
+			return template.HTML(buf.String()), nil
+		},
+	})
+}
+
+// isEmptyValue report whether the value is empty as defined by the json omitempty option
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
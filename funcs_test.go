@@ -0,0 +1,110 @@
+package renderer
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_dict(t *testing.T) {
+	m, err := dict("title", "Hi", "count", 2)
+	checkNil(t, err)
+	if m["title"] != "Hi" || m["count"] != 2 {
+		t.Errorf("unexpected dict: %v", m)
+	}
+
+	_, err = dict("title")
+	checkNotNil(t, err)
+
+	_, err = dict(1, "Hi")
+	checkNotNil(t, err)
+}
+
+func Test_HTML_include_dict(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	widget := `{{define "widget"}}<div class="widget"><h4>{{.title}}</h4></div>{{end}}`
+	ioutil.WriteFile(dir+"/widget.tmpl", []byte(widget), perm)
+	index := `{{define "homePage"}}<html>{{include "widget" (dict "title" "Hi")}}{{include "widget" (dict "title" .name)}}</html>{{end}}`
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
+	r := New(
+		Options{
+			ParseGlobPattern: dir + "/*.tmpl",
+		},
+	)
+
+	expected := `<html><div class="widget"><h4>Hi</h4></div><div class="widget"><h4>&lt;b&gt;John&lt;/b&gt;</h4></div></html>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.HTML(w, http.StatusOK, "homePage", M{"name": "<b>John</b>"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/html", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_Template_DefaultFuncs(t *testing.T) {
+	f, err := ioutil.TempFile("", "funcs-*.tpl")
+	checkNil(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`<p>{{upper .name}} {{title .role}} {{.nick | default "anonymous"}} {{formatTime "2006-01-02" .joined}}</p>`)
+	f.Close()
+
+	data := M{"name": "john", "role": "site admin", "nick": "", "joined": time.Date(2017, 10, 1, 8, 30, 0, 0, time.UTC)}
+	r := New()
+	res := httptest.NewRecorder()
+	err = r.Template(res, http.StatusOK, []string{f.Name()}, data)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `<p>JOHN Site Admin anonymous 2017-10-01</p>`)
+
+	// the FuncMap option overrides the default helpers
+	r = New(Options{
+		FuncMap: []template.FuncMap{{"upper": func(s string) string { return s + "!" }}},
+	})
+	res = httptest.NewRecorder()
+	err = r.Template(res, http.StatusOK, []string{f.Name()}, data)
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `<p>john! Site Admin anonymous 2017-10-01</p>`)
+
+	r = New(Options{DisableDefaultFuncs: true})
+	if _, ok := r.templateFuncs()["upper"]; ok {
+		t.Error("default funcs should be disabled")
+	}
+}
+
+func Test_Template_GlobalData(t *testing.T) {
+	f, err := ioutil.TempFile("", "global-*.tpl")
+	checkNil(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`<title>{{.Global.AppName}} {{.Global.Version}}{{with .page}} - {{.}}{{end}}</title>`)
+	f.Close()
+
+	r := New(Options{GlobalData: M{"AppName": "Renderer"}})
+	r.SetGlobal("Version", "v1.0")
+
+	res := httptest.NewRecorder()
+	err = r.Template(res, http.StatusOK, []string{f.Name()}, nil)
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `<title>Renderer v1.0</title>`)
+
+	res = httptest.NewRecorder()
+	err = r.Template(res, http.StatusOK, []string{f.Name()}, M{"page": "Home"})
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `<title>Renderer v1.0 - Home</title>`)
+}
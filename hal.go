@@ -0,0 +1,37 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+)
+
+// ContentHAL represents content type application/hal+json
+const ContentHAL string = "application/hal+json"
+
+// halLink describes a HAL link object
+type halLink struct {
+	Href string `json:"href"`
+}
+
+// HAL serve data as HAL resource with the links embedded as _links e.g: {"_links":{"self":{"href":"/users/1"}},"name":"John"},
+// the data must encode as a JSON object
+func (r *Render) HAL(w http.ResponseWriter, status int, v interface{}, links map[string]string) error {
+	hl := make(map[string]halLink, len(links))
+	for rel, href := range links {
+		hl[rel] = halLink{Href: href}
+	}
+	bs, err := r.json(v, jsonField{key: "_links", value: hl})
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(bs), []byte("{")) {
+		return errors.New("renderer: hal resource must be a JSON object")
+	}
+	w.Header().Set(ContentType, r.withCharset(ContentHAL))
+	return r.writeBody(w, status, bs)
+}
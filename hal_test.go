@@ -0,0 +1,35 @@
+package renderer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_HAL(t *testing.T) {
+	r := New()
+	res := httptest.NewRecorder()
+	err := r.HAL(res, http.StatusOK, user{"John Doe", 30}, map[string]string{
+		"self":    "/users/1",
+		"friends": "/users/1/friends",
+	})
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHAL+"; charset="+defaultCharSet)
+
+	var doc struct {
+		Links map[string]struct {
+			Href string `json:"href"`
+		} `json:"_links"`
+		Name string
+	}
+	checkNil(t, json.Unmarshal(res.Body.Bytes(), &doc))
+	checkBody(t, doc.Links["self"].Href, "/users/1")
+	checkBody(t, doc.Links["friends"].Href, "/users/1/friends")
+	checkBody(t, doc.Name, "John Doe")
+
+	res = httptest.NewRecorder()
+	checkNotNil(t, r.HAL(res, http.StatusOK, []user{{"John Doe", 30}}, nil))
+	checkBody(t, res.Body.String(), "")
+}
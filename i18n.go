@@ -0,0 +1,78 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+const (
+	// AcceptLanguage describes the Accept-Language header
+	AcceptLanguage string = "Accept-Language"
+
+	defaultLocale string = "en"
+)
+
+// MessageCatalog describes the localized messages as locale -> key -> text/template source,
+// pluralization can be expressed in the template e.g: {{.count}} item{{if ne .count 1}}s{{end}}
+type MessageCatalog map[string]map[string]string
+
+// message describes the JSON envelope of a localized message
+type message struct {
+	Key     string `json:"key"`
+	Locale  string `json:"locale"`
+	Message string `json:"message"`
+}
+
+// locale return the catalog locale preferred by the request which has the message key, the tags are matched
+// case-insensitively and an exact tag match is preferred to the base language match e.g: de-AT falls back to de
+func (r *Render) locale(req *http.Request, key string) string {
+	find := func(tag string) (string, bool) {
+		if _, ok := r.opts.MessageCatalog[tag][key]; ok {
+			return tag, true
+		}
+		for locale, messages := range r.opts.MessageCatalog {
+			if _, ok := messages[key]; ok && strings.EqualFold(locale, tag) {
+				return locale, true
+			}
+		}
+		return "", false
+	}
+	if req != nil {
+		for _, item := range parseAccept(req.Header.Get(AcceptLanguage)) {
+			if locale, ok := find(item.value); ok {
+				return locale
+			}
+			if locale, ok := find(strings.SplitN(item.value, "-", 2)[0]); ok {
+				return locale
+			}
+		}
+	}
+	return r.opts.DefaultLocale
+}
+
+// Message serve the message of the key localized for the request Accept-Language as JSON response,
+// the message is looked up in the default locale when the preferred locales do not have it
+func (r *Render) Message(w http.ResponseWriter, req *http.Request, status int, key string, args map[string]interface{}) error {
+	locale := r.locale(req, key)
+	src, ok := r.opts.MessageCatalog[locale][key]
+	if !ok {
+		return fmt.Errorf("renderer: message %s does not exist", key)
+	}
+
+	tmpl, err := template.New(key).Parse(src)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err = tmpl.Execute(buf, args); err != nil {
+		return err
+	}
+	return r.WithRequest(req).unwrappedJSON(w, status, message{Key: key, Locale: locale, Message: buf.String()})
+}
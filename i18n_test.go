@@ -0,0 +1,70 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Message(t *testing.T) {
+	r := New(Options{
+		MessageCatalog: MessageCatalog{
+			"en": {
+				"cart.items": `You have {{.count}} item{{if ne .count 1}}s{{end}} in your cart`,
+				"welcome":    `Welcome {{.name}}`,
+			},
+			"de": {
+				"cart.items": `Sie haben {{.count}} {{if eq .count 1}}Produkt{{else}}Produkte{{end}} im Warenkorb`,
+			},
+			"pt-BR": {
+				"welcome": `Bem-vindo {{.name}}`,
+			},
+		},
+	})
+
+	cases := []struct {
+		lang     string
+		key      string
+		args     map[string]interface{}
+		expected string
+	}{
+		{"en-US,en;q=0.9", "cart.items", M{"count": 2}, `{"key":"cart.items","locale":"en","message":"You have 2 items in your cart"}`},
+		{"en", "cart.items", M{"count": 1}, `{"key":"cart.items","locale":"en","message":"You have 1 item in your cart"}`},
+		{"de-AT, en;q=0.5", "cart.items", M{"count": 3}, `{"key":"cart.items","locale":"de","message":"Sie haben 3 Produkte im Warenkorb"}`},
+		{"de", "welcome", M{"name": "John"}, `{"key":"welcome","locale":"en","message":"Welcome John"}`},
+		{"pt-BR, en;q=0.5", "welcome", M{"name": "John"}, `{"key":"welcome","locale":"pt-BR","message":"Bem-vindo John"}`},
+		{"PT-br", "welcome", M{"name": "John"}, `{"key":"welcome","locale":"pt-BR","message":"Bem-vindo John"}`},
+	}
+
+	for _, c := range cases {
+		var err error
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.Message(w, req, http.StatusOK, c.key, c.args)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/message", nil)
+		req.Header.Set(AcceptLanguage, c.lang)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset="+defaultCharSet)
+		checkBody(t, res.Body.String(), c.expected)
+	}
+}
+
+func Test_Message_missing_key(t *testing.T) {
+	r := New()
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.Message(w, req, http.StatusOK, "missing", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/message", nil)
+	h.ServeHTTP(res, req)
+
+	checkNotNil(t, err)
+}
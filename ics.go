@@ -0,0 +1,123 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentCalendar represents content type text/calendar
+	ContentCalendar string = "text/calendar"
+
+	defaultCalendarProdID string = "-//thedevsaddam//renderer//EN"
+	icsTimeFormat         string = "20060102T150405Z"
+	icsLineLimit          int    = 75
+)
+
+type (
+	// Calendar describes an iCalendar (RFC 5545) calendar
+	Calendar struct {
+		// ProdID set the product identifier; default: -//thedevsaddam//renderer//EN
+		ProdID string
+		// Name set the calendar name as X-WR-CALNAME
+		Name   string
+		Events []Event
+	}
+
+	// Event describes an iCalendar VEVENT
+	Event struct {
+		UID         string
+		Summary     string
+		Description string
+		Location    string
+		URL         string
+		Start       time.Time
+		End         time.Time
+		Created     time.Time
+	}
+)
+
+// icsEscaper escape the iCalendar text values
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsWriter write the content lines of an iCalendar
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+// line write a content line folded at 75 octets
+func (iw *icsWriter) line(name, value string) {
+	l := name + ":" + value
+	for len(l) > icsLineLimit {
+		cut := icsLineLimit
+		// do not split a multi byte character
+		for cut > 0 && l[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if cut <= 1 {
+			// invalid UTF-8, the line is cut at the limit so that it gets shorter
+			cut = icsLineLimit
+		}
+		iw.buf.WriteString(l[:cut] + "\r\n")
+		l = " " + l[cut:]
+	}
+	iw.buf.WriteString(l + "\r\n")
+}
+
+// text write a text content line, empty values are skipped
+func (iw *icsWriter) text(name, value string) {
+	if value != "" {
+		iw.line(name, icsEscaper.Replace(value))
+	}
+}
+
+// time write a date-time content line in UTC, zero values are skipped
+func (iw *icsWriter) time(name string, t time.Time) {
+	if !t.IsZero() {
+		iw.line(name, t.UTC().Format(icsTimeFormat))
+	}
+}
+
+// Marshal return the calendar as iCalendar text
+func (c Calendar) Marshal() []byte {
+	prodID := c.ProdID
+	if prodID == "" {
+		prodID = defaultCalendarProdID
+	}
+
+	iw := new(icsWriter)
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", prodID)
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.text("X-WR-CALNAME", c.Name)
+	for _, e := range c.Events {
+		iw.line("BEGIN", "VEVENT")
+		iw.text("UID", e.UID)
+		iw.time("DTSTAMP", time.Now())
+		iw.time("CREATED", e.Created)
+		iw.time("DTSTART", e.Start)
+		iw.time("DTEND", e.End)
+		iw.text("SUMMARY", e.Summary)
+		iw.text("DESCRIPTION", e.Description)
+		iw.text("LOCATION", e.Location)
+		iw.text("URL", e.URL)
+		iw.line("END", "VEVENT")
+	}
+	iw.line("END", "VCALENDAR")
+	return iw.buf.Bytes()
+}
+
+// ICS serve calendar as text/calendar response with content-disposition value attachment
+func (r *Render) ICS(w http.ResponseWriter, status int, cal Calendar, filename string) error {
+	w.Header().Set(ContentDisposition, dispositionOf(filename, false))
+	w.Header().Set(ContentType, r.withCharset(ContentCalendar))
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, cal.Marshal())
+}
@@ -0,0 +1,81 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ICS(t *testing.T) {
+	r := New()
+	var err error
+
+	cal := Calendar{
+		Name: "Meetups",
+		Events: []Event{
+			{
+				UID:         "1@example.com",
+				Summary:     "Go meetup; Dhaka, BD",
+				Description: "Talks\nand snacks",
+				Start:       time.Date(2019, 3, 11, 10, 0, 0, 0, time.UTC),
+				End:         time.Date(2019, 3, 11, 12, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.ICS(w, http.StatusOK, cal, "events.ics")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events.ics", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentCalendar+"; charset="+defaultCharSet)
+	if got := res.Header().Get(ContentDisposition); got != "attachment; filename=events.ics" {
+		t.Errorf("content disposition missmatch. got: %s", got)
+	}
+
+	body := res.Body.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"BEGIN:VEVENT\r\nUID:1@example.com\r\n",
+		"DTSTART:20190311T100000Z\r\nDTEND:20190311T120000Z\r\n",
+		`SUMMARY:Go meetup\; Dhaka\, BD` + "\r\n",
+		`DESCRIPTION:Talks\nand snacks` + "\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("calendar should contain %q, got %q", want, body)
+		}
+	}
+}
+
+func Test_ICS_line_folding(t *testing.T) {
+	cal := Calendar{Events: []Event{{UID: "1", Summary: strings.Repeat("a", 100)}}}
+	for _, l := range strings.Split(string(cal.Marshal()), "\r\n") {
+		if len(l) > icsLineLimit {
+			t.Errorf("line should be folded at %d octets, got %d", icsLineLimit, len(l))
+		}
+	}
+}
+
+func Test_ICS_line_folding_invalid_utf8(t *testing.T) {
+	cal := Calendar{Events: []Event{{UID: "1", Summary: "a" + strings.Repeat("\x80", 200)}}}
+	done := make(chan []byte)
+	go func() { done <- cal.Marshal() }()
+	select {
+	case bs := <-done:
+		for _, l := range strings.Split(string(bs), "\r\n") {
+			if len(l) > icsLineLimit {
+				t.Errorf("line should be folded at %d octets, got %d", icsLineLimit, len(l))
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("folding invalid UTF-8 should terminate")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const (
+	// ContentJSONAPI represents content type application/vnd.api+json
+	ContentJSONAPI string = "application/vnd.api+json"
+
+	jsonAPITag       string = "jsonapi"
+	jsonAPIPrimary   string = "primary"
+	jsonAPIAttribute string = "attr"
+)
+
+// jsonAPIResource describes a JSON:API resource object
+type jsonAPIResource struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// jsonAPIDocument build a JSON:API document from a tagged struct or a slice of tagged structs.
+// The id field is tagged as `jsonapi:"primary,type"` and the attributes as `jsonapi:"attr,name"`
+func jsonAPIDocument(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return map[string]interface{}{"data": nil}, nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		data := make([]jsonAPIResource, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			res, err := jsonAPIResourceOf(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, res)
+		}
+		return map[string]interface{}{"data": data}, nil
+	}
+
+	res, err := jsonAPIResourceOf(rv)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"data": res}, nil
+}
+
+// jsonAPIResourceOf build a JSON:API resource object from a tagged struct value
+func jsonAPIResourceOf(rv reflect.Value) (jsonAPIResource, error) {
+	var res jsonAPIResource
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return res, fmt.Errorf("renderer: jsonapi resource must be a struct, got %s", rv.Kind())
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tag := rt.Field(i).Tag.Get(jsonAPITag)
+		if tag == "" {
+			continue
+		}
+		if rt.Field(i).PkgPath != "" {
+			return res, fmt.Errorf("renderer: jsonapi tag %q on unexported field %s", tag, rt.Field(i).Name)
+		}
+		parts := strings.SplitN(tag, ",", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return res, fmt.Errorf("renderer: invalid jsonapi tag %q on field %s", tag, rt.Field(i).Name)
+		}
+		switch parts[0] {
+		case jsonAPIPrimary:
+			// a pointer id is formatted by the value it points to
+			id := reflect.Indirect(rv.Field(i))
+			if !id.IsValid() {
+				return res, fmt.Errorf("renderer: jsonapi primary field %s is nil", rt.Field(i).Name)
+			}
+			res.Type = parts[1]
+			res.ID = fmt.Sprint(id.Interface())
+		case jsonAPIAttribute:
+			if res.Attributes == nil {
+				res.Attributes = make(map[string]interface{})
+			}
+			res.Attributes[parts[1]] = rv.Field(i).Interface()
+		default:
+			return res, fmt.Errorf("renderer: unsupported jsonapi tag %q on field %s", tag, rt.Field(i).Name)
+		}
+	}
+
+	if res.Type == "" {
+		return res, errors.New("renderer: jsonapi resource must have a primary field")
+	}
+	return res, nil
+}
+
+// JSONAPI serve a tagged struct or a slice of tagged structs as JSON:API document.
+// Note: the media type is sent without charset as the JSON:API specification forbids media type parameters
+func (r *Render) JSONAPI(w http.ResponseWriter, status int, resource interface{}) error {
+	doc, err := jsonAPIDocument(resource)
+	if err != nil {
+		return err
+	}
+	bs, err := r.json(doc)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set(ContentType, ContentJSONAPI)
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, bs)
+}
@@ -0,0 +1,103 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type article struct {
+	ID    int    `jsonapi:"primary,articles"`
+	Title string `jsonapi:"attr,title"`
+	Body  string `jsonapi:"attr,body"`
+	Draft bool
+}
+
+func Test_JSONAPI(t *testing.T) {
+	r := New()
+	var err error
+
+	a := article{ID: 1, Title: "Hello", Body: "World"}
+	expected := `{"data":{"type":"articles","id":"1","attributes":{"body":"World","title":"Hello"}}}`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONAPI(w, http.StatusOK, &a)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/jsonapi", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSONAPI)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_JSONAPI_collection(t *testing.T) {
+	r := New()
+	var err error
+
+	as := []article{{ID: 1, Title: "One"}, {ID: 2, Title: "Two"}}
+	expected := `{"data":[{"type":"articles","id":"1","attributes":{"body":"","title":"One"}},{"type":"articles","id":"2","attributes":{"body":"","title":"Two"}}]}`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONAPI(w, http.StatusOK, as)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/jsonapi", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_JSONAPI_without_primary(t *testing.T) {
+	r := New()
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONAPI(w, http.StatusOK, user{"John Doe", 30})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/jsonapi", nil)
+	h.ServeHTTP(res, req)
+
+	checkNotNil(t, err)
+}
+
+func Test_JSONAPI_unexported_field(t *testing.T) {
+	r := New()
+	v := struct {
+		ID    int    `jsonapi:"primary,articles"`
+		title string `jsonapi:"attr,title"`
+	}{ID: 1, title: "Hello"}
+
+	res := httptest.NewRecorder()
+	err := r.JSONAPI(res, http.StatusOK, v)
+	checkNotNil(t, err)
+	checkBody(t, res.Body.String(), "")
+}
+
+func Test_JSONAPI_pointer_primary(t *testing.T) {
+	r := New()
+	type post struct {
+		ID    *int   `jsonapi:"primary,posts"`
+		Title string `jsonapi:"attr,title"`
+	}
+	id := 7
+
+	res := httptest.NewRecorder()
+	err := r.JSONAPI(res, http.StatusOK, post{ID: &id, Title: "Hello"})
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"data":{"type":"posts","id":"7","attributes":{"title":"Hello"}}}`)
+
+	// a nil id is an error
+	res = httptest.NewRecorder()
+	err = r.JSONAPI(res, http.StatusOK, post{Title: "Hello"})
+	checkNotNil(t, err)
+	checkBody(t, res.Body.String(), "")
+}
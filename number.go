@@ -0,0 +1,80 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NumberFormatter describes a function which formats the number for the locale
+type NumberFormatter func(n float64, locale string) string
+
+// numberSeparators contain the thousands and decimal separators of the base languages
+var numberSeparators = map[string][2]string{
+	"en": {",", "."},
+	"bn": {",", "."},
+	"hi": {",", "."},
+	"ja": {",", "."},
+	"zh": {",", "."},
+	"de": {".", ","},
+	"es": {".", ","},
+	"id": {".", ","},
+	"it": {".", ","},
+	"nl": {".", ","},
+	"pt": {".", ","},
+	"tr": {".", ","},
+	"fr": {" ", ","},
+	"ru": {" ", ","},
+	"sv": {" ", ","},
+	"pl": {" ", ","},
+}
+
+// FormatNumber format the number with the thousands and decimal separators of the locale e.g: 1,234.56 for en
+// and 1.234,56 for de, unknown locales are formatted as en
+func FormatNumber(n float64, locale string) string {
+	base := strings.ToLower(strings.SplitN(strings.Replace(locale, "_", "-", -1), "-", 2)[0])
+	seps, ok := numberSeparators[base]
+	if !ok {
+		seps = numberSeparators["en"]
+	}
+	if math.IsInf(n, 0) || math.IsNaN(n) {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	s := strconv.FormatFloat(math.Abs(n), 'f', -1, 64)
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	var b strings.Builder
+	if n < 0 {
+		b.WriteByte('-')
+	}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(seps[0])
+		}
+		b.WriteRune(c)
+	}
+	if fracPart != "" {
+		b.WriteString(seps[1])
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// Number serve the number formatted for the locale as text/plain response, the formatter can be replaced
+// by the NumberFormatter option
+func (r *Render) Number(w http.ResponseWriter, status int, n float64, locale string) error {
+	format := r.opts.NumberFormatter
+	if format == nil {
+		format = FormatNumber
+	}
+	return r.String(w, status, format(n, locale))
+}
@@ -0,0 +1,65 @@
+package renderer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_FormatNumber(t *testing.T) {
+	cases := []struct {
+		n        float64
+		locale   string
+		expected string
+	}{
+		{1234.56, "en", "1,234.56"},
+		{1234.56, "de-DE", "1.234,56"},
+		{-1234567, "en_US", "-1,234,567"},
+		{123, "de", "123"},
+		{1234.5, "xx", "1,234.5"},
+	}
+	for _, c := range cases {
+		checkBody(t, FormatNumber(c.n, c.locale), c.expected)
+	}
+}
+
+func Test_Number(t *testing.T) {
+	r := New()
+
+	for locale, expected := range map[string]string{"en": "1,234.56", "de": "1.234,56"} {
+		var err error
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.Number(w, http.StatusOK, 1234.56, locale)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/number", nil)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
+		checkBody(t, res.Body.String(), expected)
+	}
+}
+
+func Test_Number_custom_formatter(t *testing.T) {
+	r := New(Options{
+		NumberFormatter: func(n float64, locale string) string {
+			return fmt.Sprintf("%s:%.1f", locale, n)
+		},
+	})
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.Number(w, http.StatusOK, 1234.56, "en")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/number", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), "en:1234.6")
+}
@@ -0,0 +1,85 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// PaginationKey describes the template data key holding the Pagination of ViewPaginated
+	PaginationKey string = "pagination"
+
+	pageParam string = "page"
+)
+
+// Pagination describes the pagination context injected into the template data by ViewPaginated
+type Pagination struct {
+	Page       int
+	PerPage    int
+	Total      int
+	TotalPages int
+	HasPrev    bool
+	HasNext    bool
+	PrevURL    string
+	NextURL    string
+}
+
+// newPagination compute the pagination of the request, the prev/next urls keep the request query with the page replaced
+func newPagination(req *http.Request, page, perPage, total int) Pagination {
+	if perPage < 1 {
+		perPage = 1
+	}
+	p := Pagination{Page: page, PerPage: perPage, Total: total}
+	p.TotalPages = (total + perPage - 1) / perPage
+	if p.TotalPages < 1 {
+		p.TotalPages = 1
+	}
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Page > p.TotalPages {
+		p.Page = p.TotalPages
+	}
+	p.HasPrev = p.Page > 1
+	p.HasNext = p.Page < p.TotalPages
+	pageURL := func(n int) string {
+		u := *req.URL
+		q := u.Query()
+		q.Set(pageParam, strconv.Itoa(n))
+		u.RawQuery = q.Encode()
+		return u.RequestURI()
+	}
+	if p.HasPrev {
+		p.PrevURL = pageURL(p.Page - 1)
+	}
+	if p.HasNext {
+		p.NextURL = pageURL(p.Page + 1)
+	}
+	return p
+}
+
+// ViewPaginated render the template like View with the Pagination injected into the data under the PaginationKey,
+// the data must be nil or a map e.g: {{with .pagination}}{{if .HasNext}}<a href="{{.NextURL}}">Next</a>{{end}}{{end}}
+func (r *Render) ViewPaginated(w http.ResponseWriter, req *http.Request, status int, name string, v interface{}, page, perPage, total int, opts ...RenderOption) error {
+	data := M{}
+	switch m := v.(type) {
+	case nil:
+	case M:
+		for k, val := range m {
+			data[k] = val
+		}
+	case map[string]interface{}:
+		for k, val := range m {
+			data[k] = val
+		}
+	default:
+		return fmt.Errorf("renderer: paginated data must be a map, got %T", v)
+	}
+	data[PaginationKey] = newPagination(req, page, perPage, total)
+	return r.WithRequest(req).View(w, status, name, data, opts...)
+}
@@ -0,0 +1,60 @@
+package renderer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_newPagination(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/users?page=2&sort=name", nil)
+
+	p := newPagination(req, 2, 10, 25)
+	if p.TotalPages != 3 || !p.HasPrev || !p.HasNext {
+		t.Errorf("unexpected pagination: %+v", p)
+	}
+	checkBody(t, p.PrevURL, "/users?page=1&sort=name")
+	checkBody(t, p.NextURL, "/users?page=3&sort=name")
+
+	p = newPagination(req, 9, 10, 0)
+	if p.Page != 1 || p.TotalPages != 1 || p.HasPrev || p.HasNext || p.PrevURL != "" || p.NextURL != "" {
+		t.Errorf("unexpected pagination: %+v", p)
+	}
+}
+
+func Test_ViewPaginated(t *testing.T) {
+	var err error
+	dir := "view"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	users := `{{define "content"}}<h3>{{.title}}</h3>{{with .pagination}}<p>Page {{.Page}} of {{.TotalPages}}</p>{{if .HasPrev}}<a href="{{.PrevURL}}">Prev</a>{{end}}{{if .HasNext}}<a href="{{.NextURL}}">Next</a>{{end}}{{end}}{{end}}`
+	ioutil.WriteFile(dir+"/users.tpl", []byte(users), perm)
+	base := `<html><body>{{ template "content" . }}</body></html>`
+	ioutil.WriteFile(dir+"/base.lout", []byte(base), perm)
+
+	r := New(
+		Options{
+			TemplateDir: "view",
+		},
+	)
+
+	expected := `<html><body><h3>Users</h3><p>Page 2 of 3</p><a href="/users?page=1">Prev</a><a href="/users?page=3">Next</a></body></html>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.ViewPaginated(w, req, http.StatusOK, "users", M{"title": "Users"}, 2, 10, 25)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users?page=2", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), expected)
+}
@@ -0,0 +1,29 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentProtobuf represents content type application/x-protobuf
+const ContentProtobuf string = "application/x-protobuf"
+
+// Protobuf serve the protocol buffers message in the binary wire format as application/x-protobuf response
+func (r *Render) Protobuf(w http.ResponseWriter, status int, msg proto.Message) error {
+	if msg == nil || (reflect.ValueOf(msg).Kind() == reflect.Ptr && reflect.ValueOf(msg).IsNil()) {
+		return errors.New("renderer: protobuf message can not be nil")
+	}
+	bs, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(ContentType, ContentProtobuf)
+	return r.writeBody(w, status, bs)
+}
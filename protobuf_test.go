@@ -0,0 +1,27 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func Test_Protobuf(t *testing.T) {
+	r := New()
+	res := httptest.NewRecorder()
+	err := r.Protobuf(res, http.StatusOK, wrapperspb.String("John Doe"))
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentProtobuf)
+
+	msg := new(wrapperspb.StringValue)
+	checkNil(t, proto.Unmarshal(res.Body.Bytes(), msg))
+	checkBody(t, msg.GetValue(), "John Doe")
+
+	var nilMsg *wrapperspb.StringValue
+	checkNotNil(t, r.Protobuf(httptest.NewRecorder(), http.StatusOK, nilMsg))
+	checkNotNil(t, r.Protobuf(httptest.NewRecorder(), http.StatusOK, nil))
+}
@@ -0,0 +1,39 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ContentPNG represents content type image/png
+const ContentPNG string = "image/png"
+
+// QRCodeFunc describes a function encoding the content as PNG QR code of size x size pixels,
+// the package does not depend on a QR code library e.g: using github.com/skip2/go-qrcode
+//
+//	func(content string, size int) ([]byte, error) {
+//		return qrcode.Encode(content, qrcode.Medium, size)
+//	}
+type QRCodeFunc func(content string, size int) ([]byte, error)
+
+// QRCode serve the content encoded by QRCodeFunc as image/png response, the content type is sent without charset
+func (r *Render) QRCode(w http.ResponseWriter, status int, content string, size int) error {
+	if r.opts.QRCodeFunc == nil {
+		return errors.New("renderer: QRCodeFunc is not set")
+	}
+	if size <= 0 {
+		return errors.New("renderer: qr code size must be positive")
+	}
+	bs, err := r.opts.QRCodeFunc(content, size)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set(ContentType, ContentPNG)
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, bs)
+}
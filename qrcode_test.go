@@ -0,0 +1,40 @@
+package renderer
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_QRCode(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	var got string
+	var gotSize int
+	r := New(Options{
+		QRCodeFunc: func(content string, size int) ([]byte, error) {
+			got, gotSize = content, size
+			return png, nil
+		},
+	})
+
+	res := httptest.NewRecorder()
+	err := r.QRCode(res, http.StatusOK, "otpauth://totp/renderer?secret=JBSWY3DPEHPK3PXP", 256)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentPNG)
+	if !bytes.Equal(res.Body.Bytes(), png) {
+		t.Errorf("unexpected body: %q", res.Body.Bytes())
+	}
+	if got != "otpauth://totp/renderer?secret=JBSWY3DPEHPK3PXP" || gotSize != 256 {
+		t.Errorf("generator called with %q, %d", got, gotSize)
+	}
+}
+
+func Test_QRCode_without_func(t *testing.T) {
+	r := New()
+	res := httptest.NewRecorder()
+	err := r.QRCode(res, http.StatusOK, "renderer", 256)
+	checkNotNil(t, err)
+	checkBody(t, res.Body.String(), "")
+}
@@ -0,0 +1,146 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Range describes the Range header
+	Range string = "Range"
+	// ContentRange describes the Content-Range header
+	ContentRange string = "Content-Range"
+	// AcceptRanges describes the Accept-Ranges header
+	AcceptRanges string = "Accept-Ranges"
+
+	bytesUnit string = "bytes"
+)
+
+var (
+	// errInvalidRange describes a Range header which is ignored e.g: malformed or multiple ranges
+	errInvalidRange = errors.New("renderer: invalid range")
+	// errUnsatisfiableRange describes a Range header which does not overlap the content
+	errUnsatisfiableRange = errors.New("renderer: unsatisfiable range")
+)
+
+// parseRange parse a single byte range e.g: bytes=0-4, bytes=5- or bytes=-5 and return the inclusive
+// offsets of the range clamped to the size
+func parseRange(header string, size int64) (start, end int64, err error) {
+	spec := strings.TrimPrefix(header, bytesUnit+"=")
+	if spec == header || strings.Contains(spec, ",") {
+		return 0, 0, errInvalidRange
+	}
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0, 0, errInvalidRange
+	}
+	first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+	if first == "" {
+		// suffix range, the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, errInvalidRange
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, errUnsatisfiableRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errInvalidRange
+	}
+	end = size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, errInvalidRange
+		}
+	}
+	if start >= size {
+		return 0, 0, errUnsatisfiableRange
+	}
+	if end >= size {
+		end = size - 1
+	}
+	return start, end, nil
+}
+
+// sniffContentType detect the content type of the first 512 bytes of the reader and rewind it
+func sniffContentType(rs io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(rs, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err = rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// writeRange serve the byte range requested by the Range header of the bound request as 206 Partial Content,
+// the reader is left at its beginning and ok is false when the response is not ranged
+func (r *Render) writeRange(w http.ResponseWriter, status int, rs io.ReadSeeker) (ok bool, err error) {
+	w.Header().Set(AcceptRanges, bytesUnit)
+	if r.req == nil || r.status(status) != http.StatusOK || r.req.Header.Get(Range) == "" {
+		return false, nil
+	}
+
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return true, err
+	}
+	start, end, err := parseRange(r.req.Header.Get(Range), size)
+	switch err {
+	case errInvalidRange:
+		_, err = rs.Seek(0, io.SeekStart)
+		return false, err
+	case errUnsatisfiableRange:
+		w.Header().Set(ContentRange, fmt.Sprintf("%s */%d", bytesUnit, size))
+		w.Header().Del(ContentLength)
+		r.writeHeader(w, http.StatusRequestedRangeNotSatisfiable)
+		return true, nil
+	}
+
+	if _, err = rs.Seek(start, io.SeekStart); err != nil {
+		return true, err
+	}
+	w.Header().Set(ContentRange, fmt.Sprintf("%s %d-%d/%d", bytesUnit, start, end, size))
+	w.Header().Set(ContentLength, strconv.FormatInt(end-start+1, 10))
+	r.writeHeader(w, http.StatusPartialContent)
+	_, err = io.CopyN(w, rs, end-start+1)
+	return true, err
+}
+
+// ServeBytes serve the in-memory content with http.ServeContent so Range and conditional requests are supported,
+// Last-Modified is set to now and the Content-Type is detected from the extension of name or the content.
+// The content is served as it is with status when status is not 200 OK
+func (r *Render) ServeBytes(w http.ResponseWriter, req *http.Request, status int, name string, b []byte) error {
+	rr := r.WithRequest(req)
+	if status = r.status(status); status != http.StatusOK {
+		if w.Header().Get(ContentType) == "" {
+			w.Header().Set(ContentType, http.DetectContentType(b))
+		}
+		rr.writeHeader(w, status)
+		_, err := w.Write(b)
+		return err
+	}
+	rr.setCommonHeaders(w)
+	http.ServeContent(w, req, name, time.Now(), bytes.NewReader(b))
+	return nil
+}
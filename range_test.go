@@ -0,0 +1,107 @@
+package renderer
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_File_range(t *testing.T) {
+	data := "Hello, renderer!"
+	f, err := ioutil.TempFile("", "range-*.txt")
+	checkNil(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(data)
+	f.Close()
+
+	r := New()
+	render := map[string]func(r *Render, w http.ResponseWriter) error{
+		"file": func(r *Render, w http.ResponseWriter) error {
+			return r.File(w, http.StatusOK, strings.NewReader(data), "hello.txt", true)
+		},
+		"file-view": func(r *Render, w http.ResponseWriter) error {
+			return r.FileView(w, http.StatusOK, f.Name(), "hello.txt")
+		},
+	}
+
+	for name, fn := range render {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+name, nil)
+		req.Header.Set(Range, "bytes=0-4")
+		checkNil(t, fn(r.WithRequest(req), res))
+		if res.Code != http.StatusPartialContent {
+			t.Errorf("%s: expected status %d, got %d", name, http.StatusPartialContent, res.Code)
+		}
+		checkBody(t, res.Header().Get(ContentRange), "bytes 0-4/16")
+		checkBody(t, res.Header().Get(AcceptRanges), "bytes")
+		checkContentType(t, res.Header().Get(ContentType), r.opts.ContentText)
+		checkBody(t, res.Body.String(), "Hello")
+		checkBody(t, res.Header().Get(ContentLength), "5")
+
+		// unsatisfiable range
+		res = httptest.NewRecorder()
+		req.Header.Set(Range, "bytes=20-")
+		checkNil(t, fn(r.WithRequest(req), res))
+		if res.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Errorf("%s: expected status %d, got %d", name, http.StatusRequestedRangeNotSatisfiable, res.Code)
+		}
+		checkBody(t, res.Header().Get(ContentRange), "bytes */16")
+		checkBody(t, res.Header().Get(ContentLength), "")
+		checkBody(t, res.Body.String(), "")
+	}
+}
+
+func Test_File_range_not_seekable(t *testing.T) {
+	r := New()
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/file", nil)
+	req.Header.Set(Range, "bytes=0-4")
+	reader := struct{ io.Reader }{strings.NewReader("Hello, renderer!")}
+	err := r.WithRequest(req).File(res, http.StatusOK, reader, "hello.txt", true)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), "Hello, renderer!")
+}
+
+func Test_parseRange(t *testing.T) {
+	cases := map[string][3]interface{}{
+		"bytes=0-4":   {int64(0), int64(4), nil},
+		"bytes=10-":   {int64(10), int64(15), nil},
+		"bytes=-6":    {int64(10), int64(15), nil},
+		"bytes=5-100": {int64(5), int64(15), nil},
+		"bytes=16-":   {int64(0), int64(0), errUnsatisfiableRange},
+		"bytes=0-1,3": {int64(0), int64(0), errInvalidRange},
+		"bytes=4-2":   {int64(0), int64(0), errInvalidRange},
+		"items=0-4":   {int64(0), int64(0), errInvalidRange},
+	}
+	for header, want := range cases {
+		start, end, err := parseRange(header, 16)
+		if start != want[0] || end != want[1] || err != want[2] {
+			t.Errorf("parseRange(%q). got: %d %d %v want: %v", header, start, end, err, want)
+		}
+	}
+}
+
+func Test_ServeBytes(t *testing.T) {
+	r := New()
+	report := []byte("id,name\n1,John\n2,Jane\n")
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/report", nil)
+	req.Header.Set(Range, "bytes=0-6")
+	err := r.ServeBytes(res, req, http.StatusOK, "report.csv", report)
+	checkNil(t, err)
+	if res.Code != http.StatusPartialContent {
+		t.Errorf("expected status %d, got %d", http.StatusPartialContent, res.Code)
+	}
+	checkBody(t, res.Header().Get(ContentRange), "bytes 0-6/22")
+	checkContentType(t, res.Header().Get(ContentType), "text/csv; charset=utf-8")
+	if res.Header().Get("Last-Modified") == "" {
+		t.Error("Last-Modified should be set")
+	}
+	checkBody(t, res.Body.String(), "id,name")
+}
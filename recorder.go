@@ -0,0 +1,63 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import "net/http"
+
+// StatusRecorder describes a http.ResponseWriter which records the written status and the number of body bytes,
+// useful for middleware that logs the responses
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// Wrap return a StatusRecorder wrapping the http.ResponseWriter
+func Wrap(w http.ResponseWriter) *StatusRecorder {
+	if sr, ok := w.(*StatusRecorder); ok {
+		return sr
+	}
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+// WriteHeader record the status and write it to the underlying writer
+func (s *StatusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write record the number of bytes and write them to the underlying writer
+func (s *StatusRecorder) Write(bs []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(bs)
+	s.bytes += n
+	return n, err
+}
+
+// Flush flush the underlying writer if it supports http.Flusher
+func (s *StatusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap return the underlying http.ResponseWriter
+func (s *StatusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Status return the written status, 0 means nothing written yet
+func (s *StatusRecorder) Status() int {
+	return s.status
+}
+
+// Bytes return the number of written body bytes
+func (s *StatusRecorder) Bytes() int {
+	return s.bytes
+}
@@ -0,0 +1,46 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_StatusRecorder(t *testing.T) {
+	r := New()
+	var err error
+	var rec *StatusRecorder
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec = Wrap(w)
+		err = r.JSON(rec, http.StatusCreated, user{"John Doe", 30})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	if rec.Status() != http.StatusCreated {
+		t.Errorf("recorded status missmatch. got: %d want: %d", rec.Status(), http.StatusCreated)
+	}
+	if rec.Bytes() != res.Body.Len() {
+		t.Errorf("recorded bytes missmatch. got: %d want: %d", rec.Bytes(), res.Body.Len())
+	}
+	if res.Code != http.StatusCreated {
+		t.Error("http status code should be 201")
+	}
+}
+
+func Test_StatusRecorder_implicit_ok(t *testing.T) {
+	res := httptest.NewRecorder()
+	rec := Wrap(res)
+	rec.Write([]byte("hello"))
+
+	if rec.Status() != http.StatusOK {
+		t.Errorf("recorded status missmatch. got: %d want: %d", rec.Status(), http.StatusOK)
+	}
+	if Wrap(rec) != rec {
+		t.Error("wrapping a recorder should return the same recorder")
+	}
+}
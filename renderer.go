@@ -10,19 +10,35 @@ package renderer
 
 import (
 	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 
+	"github.com/BurntSushi/toml"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -37,15 +53,47 @@ const (
 	ContentXML string = "application/xml"
 	// ContentYAML represents content type application/x-yaml
 	ContentYAML string = "application/x-yaml"
+	// ContentTOML represents content type application/toml
+	ContentTOML string = "application/toml"
 	// ContentHTML represents content type text/html
 	ContentHTML string = "text/html"
 	// ContentText represents content type text/plain
 	ContentText string = "text/plain"
 	// ContentBinary represents content type application/octet-stream
 	ContentBinary string = "application/octet-stream"
+	// ContentSVG represents content type image/svg+xml
+	ContentSVG string = "image/svg+xml"
+	// ContentNDJSON represents content type application/x-ndjson
+	ContentNDJSON string = "application/x-ndjson"
+
+	// FormatJSON describes the JSON format of Encode
+	FormatJSON string = "json"
+	// FormatXML describes the XML format of Encode
+	FormatXML string = "xml"
+	// FormatYAML describes the YAML format of Encode
+	FormatYAML string = "yaml"
+	// FormatTOML describes the TOML format of Encode
+	FormatTOML string = "toml"
+
+	// APIVersionHeader describes the X-API-Version header
+	APIVersionHeader string = "X-API-Version"
 
 	// ContentDisposition describes contentDisposition
 	ContentDisposition string = "Content-Disposition"
+	// ContentMD5 describes the Content-MD5 header
+	ContentMD5 string = "Content-MD5"
+	// Digest describes the Digest header
+	Digest string = "Digest"
+	// ContentSecurityPolicy describes the Content-Security-Policy header
+	ContentSecurityPolicy string = "Content-Security-Policy"
+	// ContentSecurityPolicyReportOnly describes the Content-Security-Policy-Report-Only header
+	ContentSecurityPolicyReportOnly string = "Content-Security-Policy-Report-Only"
+	// LastModified describes the Last-Modified header
+	LastModified string = "Last-Modified"
+	// CacheControl describes the Cache-Control header
+	CacheControl string = "Cache-Control"
+	// cacheControlImmutable describes the Cache-Control value of fingerprinted assets
+	cacheControlImmutable string = "public, max-age=31536000, immutable"
 	// contentDispositionInline describes content disposition type
 	contentDispositionInline string = "inline"
 	// contentDispositionAttachment describes content disposition type
@@ -58,6 +106,10 @@ const (
 	defaultLayoutExt          string = "lout"
 	defaultTemplateLeftDelim  string = "{{"
 	defaultTemplateRightDelim string = "}}"
+	defaultCacheMaxEntries    int    = 1000
+	defaultIndent             string = " "
+	globalDataKey             string = "Global"
+	defaultSVGDeclaration     string = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
 )
 
 type (
@@ -76,31 +128,114 @@ type (
 		ContentXML string
 		// ContentYAML represents the Content-Type for YAML
 		ContentYAML string
+		// ContentTOML represents the Content-Type for TOML
+		ContentTOML string
 		// ContentHTML represents the Content-Type for HTML
 		ContentHTML string
 		// ContentText represents the Content-Type for Text
 		ContentText string
 		// ContentBinary represents the Content-Type for octet-stream
 		ContentBinary string
+		// StatusContentType set the Content-Type of JSON by the status, the keys are status codes or status
+		// classes 1-5 and the exact status wins e.g: map[int]string{4: "application/problem+json"}
+		StatusContentType map[int]string
 
 		// UnEscapeHTML set UnEscapeHTML for JSON; default false
 		UnEscapeHTML bool
+		// ForceArray set JSON to accept only slice or array data, other data returns an error; default false
+		ForceArray bool
+		// ForceObject set JSON to accept only struct or map data, other data returns an error; default false
+		ForceObject bool
+		// APIVersion set the X-API-Version header of every response; default empty means disabled
+		APIVersion string
+		// APIVersionField set JSON to add the APIVersion under the "version" key of the object data; default false
+		APIVersionField bool
+		// TypeTag set JSON to tag the struct data with its Go type name under the "type" key; default false
+		TypeTag bool
+		// JSONSchema contain the JSON Schema the JSON output is validated against in Debug mode
+		JSONSchema []byte
+		// JSONSchemaValidator set the validator used to validate the JSON output against JSONSchema in Debug mode
+		JSONSchemaValidator func(schema, document []byte) error
+		// ASCIIOnlyJSON set escape all the non-ASCII characters in JSON as \uXXXX; default false
+		ASCIIOnlyJSON bool
 		// DisableCharset set DisableCharset in Response Content-Type
 		DisableCharset bool
-		// Debug set the debug mode. if debug is true then every time "VIEW" call parse the templates
+		// ErrorHandler set the handler writing the response when HTML, View or Template fail to render, the request
+		// is the one bound by WithRequest and may be nil. The error is returned to the caller as well, use a handler
+		// writing nothing to let the caller respond; default: InternalServerError
+		ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+		// DetectHTMLCharset set the charset of the HTML, View and Template Content-Type to the charset declared by
+		// the <meta charset> of the rendered html; default false
+		DetectHTMLCharset bool
+		// UpperCaseCharset set the charset in Response Content-Type in upper case e.g: UTF-8; default false
+		UpperCaseCharset bool
+		// Debug set the debug mode. if debug is true then every time "VIEW" and "HTML" call parse the templates
 		Debug bool
+		// Logger set the logger the template parse and execute errors are reported to; default nil means the parse
+		// errors of Debug mode are logged by the standard logger and the execute errors are only returned
+		Logger Logger
+		// DefaultStatus set the status used when a method is called with the status 0; default: 200
+		DefaultStatus int
 		// JSONIndent set JSON Indent in response; default false
 		JSONIndent bool
+		// JSONIndentValue set the string used to indent the JSON e.g: two spaces or a tab; default: a single space
+		JSONIndentValue string
+		// JSONEnvelope set wrap the data of JSON in the envelope {"data":...,"error":null} and the message of Error
+		// in {"data":null,"error":...}, Health, Message and LongPoll are not wrapped; default false
+		JSONEnvelope bool
+		// JSONMarshaler set the marshaler used to encode JSON e.g: jsoniter or go-json; default encoding/json
+		// Note: the marshaler output is used as is, JSONIndent applies to the default marshaler only
+		JSONMarshaler func(v interface{}) ([]byte, error)
+		// JSONMaxIndentDepth set the depth after which indented JSON is kept compact; default 0 means no limit
+		JSONMaxIndentDepth int
+		// TimeFormat set the layout used to format the time.Time values in JSON e.g: time.RFC3339
+		TimeFormat string
+		// Deterministic set JSON to sort the keys of the objects encoded by json.Marshaler as encoding/json sorts the
+		// map keys but keeps the struct fields in declaration order, and TimeFormat to format the time in UTC so that
+		// the output is stable e.g: for golden files; default false
+		Deterministic bool
 		// XMLIndent set XML Indent in response; default false
 		XMLIndent bool
+		// YAMLFlow set YAML in the compact flow style on a single line e.g: {"name": "John", "tags": ["a", "b"]};
+		// default false, the block style
+		YAMLFlow bool
+		// XMLIndentValue set the string used to indent the XML; default: a single space
+		XMLIndentValue string
+		// XMLMapRoot set the root element XML encode the maps under e.g: response, the entries are encoded as the
+		// child elements sorted by key; default empty means the maps are passed to encoding/xml which can not encode them
+		XMLMapRoot string
+		// TOMLIndent set TOML Indent of the nested tables in response; default false
+		TOMLIndent bool
+		// AutoCloseReader set close the reader passed to File and Binary after copying when it is an io.Closer; default false
+		AutoCloseReader bool
+		// AddDigest set the Content-MD5 and Digest headers computed from the content of File, Binary, FileView and FileDownload; default false
+		AddDigest bool
+		// StreamDigest set the Digest trailer computed from the body sent by StreamGzip; default false
+		StreamDigest bool
+		// CSP set the Content-Security-Policy header of the HTML responses e.g: default-src 'self'
+		CSP string
+		// CSPReportOnly set send the CSP as Content-Security-Policy-Report-Only so violations are reported
+		// without being enforced; default false
+		CSPReportOnly bool
+		// DetectContentType set detect the Content-Type of Binary and File from the filename extension, then from the
+		// content; default false means Binary is served as application/octet-stream and File as the sniffed type
+		DetectContentType bool
+		// SVGDeclaration set prepend the xml declaration to SVG when it is missing; default false
+		SVGDeclaration bool
 
 		// JSONPrefix set Prefix in JSON response
 		JSONPrefix string
 		// XMLPrefix set Prefix in XML response
 		XMLPrefix string
+		// XMLHeader set the XML declaration of the XML response e.g: <?xml version="1.0" encoding="UTF-8"?>, it takes
+		// precedence over XMLPrefix; use the XMLHeader method with an empty string to omit the declaration
+		XMLHeader string
 
 		// TemplateDir set the Template directory
 		TemplateDir string
+		// FileSystem set the filesystem the templates of TemplateDir, ParseGlobPattern and Template are loaded from
+		// e.g: an embed.FS, the paths are slash separated; default nil means the OS filesystem
+		FileSystem fs.FS
 		// TemplateExtension set the Template extension
 		TemplateExtension string
 		// LeftDelim set template left delimiter default is {{
@@ -111,8 +246,102 @@ type (
 		LayoutExtension string
 		// FuncMap contain function map for template
 		FuncMap []template.FuncMap
-		// ParseGlobPattern contain parse glob pattern
+		// ParseGlobPattern contain parse glob pattern, a ** pattern e.g: views/**/*.html parses the nested
+		// directories recursively and names the templates by their relative path e.g: admin/users.html
 		ParseGlobPattern string
+		// ParseGlobPatterns contain more parse glob patterns parsed into the same set as ParseGlobPattern e.g:
+		// views/*.html and views/partials/*.html
+		ParseGlobPatterns []string
+
+		// SnapshotDir set the directory where the rendered output of HTML and View is also written, the
+		// file is named by the template name e.g: home.html; default empty means disabled
+		SnapshotDir string
+
+		// CacheRenderedHTML set minify and cache the rendered output of HTML and View by the key of the WithCacheKey
+		// option or the CacheKeyer data, the output without a key is not cached; default false
+		CacheRenderedHTML bool
+		// CacheTTL set the lifetime of a cached html output; default 0 means never expire
+		CacheTTL time.Duration
+		// CacheMaxEntries set the maximum number of cached html output; default 1000
+		CacheMaxEntries int
+		// GlobalData contain the site wide values available in the HTML, View and Template templates as .Global
+		// e.g: {{.Global.AppName}}, the data of the call must be a map or nil
+		GlobalData map[string]interface{}
+		// DisableDefaultFuncs set skip merging DefaultFuncs into the templates; default false
+		DisableDefaultFuncs bool
+		// TemplateCacheSize set the maximum number of template sets parsed by Template kept in memory, the least
+		// recently used set is evicted; default 0 means the files are parsed on every call
+		TemplateCacheSize int
+		// BufferHint set the initial capacity in bytes of the buffer used by HTML, View, Template, XMLMap and
+		// Properties so that large output does not grow the buffer repeatedly; default 0
+		BufferHint int
+		// WriterWrappers set the writers wrapping the response body writer, applied in order so the first wrapper
+		// is the closest to the response e.g: a byte counter. The streaming methods write without the wrappers
+		WriterWrappers []func(io.Writer) io.Writer
+		// MaxTemplateOutput set the maximum size in bytes of the output of HTML, View and Template, the execution
+		// is aborted with ErrTemplateOutputTooLarge once exceeded; default 0 means no limit
+		MaxTemplateOutput int
+
+		// IncludeStatusCode set include the numeric status code in the Error body; default false
+		IncludeStatusCode bool
+		// IncludeStatusText set include the status reason phrase in the Error body; default false
+		IncludeStatusText bool
+
+		// EchoRequestID set echo the X-Request-Id of the request bound by WithRequest, generated when missing; default false
+		EchoRequestID bool
+
+		// EnableGzip set gzip compress the response of JSON, XML, YAML, HTML, View and Template when the
+		// request bound by WithRequest accepts gzip. The body is sent uncompressed when the client does not
+		// accept gzip or the Render is not bound to a request; default false
+		EnableGzip bool
+		// Compressors set the optional content codings e.g: ZstdCompressor{Level: 3}, preferred in order over
+		// gzip when the request bound by WithRequest accepts them
+		Compressors []Compressor
+		// GzipMinSize set the minimum body size in bytes to be compressed; default 0
+		GzipMinSize int
+		// CompressionDict set the shared dictionary used by the compressors supporting one e.g: ZstdCompressor
+		// so that the similar responses compress better, the client must decompress with the same dictionary.
+		// gzip has no preset dictionary in its format and ignores it
+		CompressionDict []byte
+		// EnableETag set the ETag header computed from the uncompressed body of JSON, XML, YAML, HTML, View
+		// and Template, the ETag of a compressed body is suffixed with the encoding. 304 Not Modified is sent
+		// without a body when the If-None-Match header of the bound request (WithRequest) matches; default false
+		EnableETag bool
+
+		// NumberFormatter set the formatter used by Number; default: FormatNumber
+		NumberFormatter NumberFormatter
+
+		// MessageCatalog contain the localized messages served by Message
+		MessageCatalog MessageCatalog
+		// DefaultLocale set the fallback locale of Message; default: en
+		DefaultLocale string
+
+		// CSRFContextKey set the request context key used to lookup the CSRF token; default: CSRFTokenKey
+		CSRFContextKey interface{}
+
+		// DiffFunc set the function computing the changes rendered by JSONDiffHTML; default: JSONDiff
+		DiffFunc DiffFunc
+
+		// QRCodeFunc set the function encoding the PNG QR code served by QRCode
+		QRCodeFunc QRCodeFunc
+
+		// PostRenderWebhook set the url the JSON payload is posted to in a goroutine after the body is written, it is
+		// not posted for a HEAD request and for 304 Not Modified
+		PostRenderWebhook string
+		// PostRenderWebhookError set the callback invoked when posting to PostRenderWebhook fails
+		PostRenderWebhookError func(err error)
+
+		// CookieSecure set the Secure attribute of the cookies set by the package; default false
+		CookieSecure bool
+		// CookieSameSite set the SameSite attribute of the cookies set by the package; default: http.SameSiteLaxMode
+		CookieSameSite http.SameSite
+		// CookieDomain set the Domain attribute of the cookies set by the package; default empty means the host
+		CookieDomain string
+
+		// FlashSecret set the key used to sign the flash cookie of RedirectWithFlash
+		FlashSecret []byte
+		// FlashMaxAge set the life time of the flash cookie; default: 1 minute
+		FlashMaxAge time.Duration
 	}
 
 	// Render describes a renderer type
@@ -120,10 +349,108 @@ type (
 		opts          Options
 		templates     map[string]*template.Template
 		globTemplates *template.Template
+		globExec      *template.Template
+		viewExec      map[string]*template.Template
 		headers       map[string]string
+		htmlCache     *htmlCache
+		templateSets  *templateCache
+		parseMu       *sync.Mutex
+		req           *http.Request
+		cookies       []*http.Cookie
+	}
+
+	// ContextKey describes the type of the request context keys used by the package
+	ContextKey string
+
+	// Logger describes the logger the template errors are reported to e.g: *log.Logger
+	Logger interface {
+		Printf(format string, args ...interface{})
+	}
+
+	// errorBody describes the JSON body of Error
+	errorBody struct {
+		Error      string `json:"error"`
+		Status     int    `json:"status,omitempty"`
+		StatusText string `json:"status_text,omitempty"`
+	}
+
+	// RenderOption describes a per call option of HTML, View and Template
+	RenderOption func(*renderConfig)
+
+	// renderConfig describes the per call configuration built from the RenderOption
+	renderConfig struct {
+		funcs    template.FuncMap
+		cacheKey string
+	}
+
+	// CacheKeyer describes the template data which tells the key its rendered html is cached by when
+	// CacheRenderedHTML is set
+	CacheKeyer interface {
+		CacheKey() string
 	}
 )
 
+// WithFuncs merge the FuncMap into the template functions for a single call only. The functions used by HTML and View
+// templates must also be declared (e.g: as a placeholder) in the FuncMap option so the templates can be parsed
+func WithFuncs(fmap template.FuncMap) RenderOption {
+	return func(c *renderConfig) {
+		if c.funcs == nil {
+			c.funcs = template.FuncMap{}
+		}
+		for k, f := range fmap {
+			c.funcs[k] = f
+		}
+	}
+}
+
+// WithCacheKey set the key the rendered html is cached by for a single call when CacheRenderedHTML is set, it must
+// identify everything the output depends on e.g: the user id and the page version
+func WithCacheKey(key string) RenderOption {
+	return func(c *renderConfig) {
+		c.cacheKey = key
+	}
+}
+
+// cacheKeyOf return the rendered html cache key of the template from the WithCacheKey option or the CacheKeyer data,
+// the output is not cached without an explicit key or with per call funcs
+func (c *renderConfig) cacheKeyOf(key string, v interface{}) string {
+	if len(c.funcs) > 0 {
+		return ""
+	}
+	explicit := c.cacheKey
+	if k, ok := v.(CacheKeyer); ok && explicit == "" {
+		explicit = k.CacheKey()
+	}
+	if explicit == "" {
+		return ""
+	}
+	return key + ":" + explicit
+}
+
+// newRenderConfig build the per call configuration from the options
+func newRenderConfig(opts []RenderOption) *renderConfig {
+	c := new(renderConfig)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// executable return the template to execute, when per call funcs are set a clone of the never executed master is returned
+func (r *Render) executable(c *renderConfig, master, exec *template.Template) (*template.Template, error) {
+	if len(c.funcs) == 0 {
+		return exec, nil
+	}
+	t, err := master.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return r.bindInclude(t.Funcs(c.funcs)), nil
+}
+
+// CSRFTokenKey is the default request context key of the CSRF token
+const CSRFTokenKey ContextKey = "csrfToken"
+
 // New return a new instance of a pointer to Render
 func New(opts ...Options) *Render {
 	var opt Options
@@ -134,19 +461,33 @@ func New(opts ...Options) *Render {
 	r := &Render{
 		opts:      opt,
 		templates: make(map[string]*template.Template),
+		viewExec:  make(map[string]*template.Template),
+		parseMu:   new(sync.Mutex),
 	}
 
 	// build options for the Render instance
 	r.buildOptions()
 
-	// if TemplateDir is not empty then call the parseTemplates
+	if r.opts.CacheRenderedHTML {
+		r.htmlCache = newHTMLCache(r.opts.CacheTTL, r.opts.CacheMaxEntries)
+	}
+	if r.opts.TemplateCacheSize > 0 {
+		r.templateSets = newTemplateCache(r.opts.TemplateCacheSize)
+	}
+
+	// if TemplateDir is not empty then call the parseTemplates, the parse errors are logged and the
+	// templates are reparsed on render in Debug mode
 	if r.opts.TemplateDir != "" {
-		r.parseTemplates()
+		if err := r.parseTemplates(); err != nil {
+			r.logf("renderer: %v", err)
+		}
 	}
 
-	// ParseGlobPattern is not empty then parse template with pattern
-	if r.opts.ParseGlobPattern != "" {
-		r.parseGlob()
+	// ParseGlobPattern or ParseGlobPatterns is not empty then parse template with patterns
+	if len(r.globPatterns()) > 0 {
+		if err := r.parseGlob(); err != nil {
+			r.logf("renderer: %v", err)
+		}
 	}
 
 	return r
@@ -162,6 +503,13 @@ func (r *Render) buildOptions() {
 		r.opts.JSONPrefix = defaultJSONPrefix
 	}
 
+	if r.opts.ErrorHandler == nil {
+		r.opts.ErrorHandler = InternalServerError
+	}
+
+	if r.opts.XMLHeader != "" {
+		r.opts.XMLPrefix = r.opts.XMLHeader
+	}
 	if r.opts.XMLPrefix == "" {
 		r.opts.XMLPrefix = defaultXMLPrefix
 	}
@@ -186,10 +534,43 @@ func (r *Render) buildOptions() {
 		r.opts.RightDelim = defaultTemplateRightDelim
 	}
 
+	if r.opts.DefaultStatus == 0 {
+		r.opts.DefaultStatus = http.StatusOK
+	}
+
+	if r.opts.JSONIndentValue == "" {
+		r.opts.JSONIndentValue = defaultIndent
+	}
+
+	if r.opts.XMLIndentValue == "" {
+		r.opts.XMLIndentValue = defaultIndent
+	}
+
+	if r.opts.CacheMaxEntries == 0 {
+		r.opts.CacheMaxEntries = defaultCacheMaxEntries
+	}
+
+	if r.opts.DefaultLocale == "" {
+		r.opts.DefaultLocale = defaultLocale
+	}
+
+	if r.opts.CSRFContextKey == nil {
+		r.opts.CSRFContextKey = CSRFTokenKey
+	}
+
+	if r.opts.CookieSameSite == 0 {
+		r.opts.CookieSameSite = http.SameSiteLaxMode
+	}
+
+	if r.opts.FlashMaxAge == 0 {
+		r.opts.FlashMaxAge = defaultFlashMaxAge
+	}
+
 	r.opts.ContentJSON = ContentJSON
 	r.opts.ContentJSONP = ContentJSONP
 	r.opts.ContentXML = ContentXML
 	r.opts.ContentYAML = ContentYAML
+	r.opts.ContentTOML = ContentTOML
 	r.opts.ContentHTML = ContentHTML
 	r.opts.ContentText = ContentText
 	r.opts.ContentBinary = ContentBinary
@@ -200,13 +581,26 @@ func (r *Render) buildOptions() {
 }
 
 func (r *Render) enableCharset() {
-	r.opts.ContentJSON = fmt.Sprintf("%s; charset=%s", r.opts.ContentJSON, r.opts.Charset)
-	r.opts.ContentJSONP = fmt.Sprintf("%s; charset=%s", r.opts.ContentJSONP, r.opts.Charset)
-	r.opts.ContentXML = fmt.Sprintf("%s; charset=%s", r.opts.ContentXML, r.opts.Charset)
-	r.opts.ContentYAML = fmt.Sprintf("%s; charset=%s", r.opts.ContentYAML, r.opts.Charset)
-	r.opts.ContentHTML = fmt.Sprintf("%s; charset=%s", r.opts.ContentHTML, r.opts.Charset)
-	r.opts.ContentText = fmt.Sprintf("%s; charset=%s", r.opts.ContentText, r.opts.Charset)
-	r.opts.ContentBinary = fmt.Sprintf("%s; charset=%s", r.opts.ContentBinary, r.opts.Charset)
+	r.opts.ContentJSON = r.withCharset(r.opts.ContentJSON)
+	r.opts.ContentJSONP = r.withCharset(r.opts.ContentJSONP)
+	r.opts.ContentXML = r.withCharset(r.opts.ContentXML)
+	r.opts.ContentYAML = r.withCharset(r.opts.ContentYAML)
+	r.opts.ContentTOML = r.withCharset(r.opts.ContentTOML)
+	r.opts.ContentHTML = r.withCharset(r.opts.ContentHTML)
+	r.opts.ContentText = r.withCharset(r.opts.ContentText)
+	r.opts.ContentBinary = r.withCharset(r.opts.ContentBinary)
+}
+
+// withCharset append the charset to the content type unless it is disabled or already present
+func (r *Render) withCharset(contentType string) string {
+	if r.opts.DisableCharset || strings.Contains(contentType, "charset=") {
+		return contentType
+	}
+	charset := r.opts.Charset
+	if r.opts.UpperCaseCharset {
+		charset = strings.ToUpper(charset)
+	}
+	return fmt.Sprintf("%s; charset=%s", contentType, charset)
 }
 
 // DisableCharset change the DisableCharset for JSON on the fly
@@ -224,12 +618,37 @@ func (r *Render) JSONIndent(b bool) *Render {
 	return r
 }
 
+// JSONMarshaler change the JSONMarshaler for JSON on the fly
+func (r *Render) JSONMarshaler(f func(v interface{}) ([]byte, error)) *Render {
+	r.opts.JSONMarshaler = f
+	return r
+}
+
 // XMLIndent change the XMLIndent for XML on the fly
 func (r *Render) XMLIndent(b bool) *Render {
 	r.opts.XMLIndent = b
 	return r
 }
 
+// XMLHeader change the XML declaration for XML on the fly, an empty string omit the declaration
+func (r *Render) XMLHeader(h string) *Render {
+	r.opts.XMLHeader = h
+	r.opts.XMLPrefix = h
+	return r
+}
+
+// Gzip change the EnableGzip on the fly
+func (r *Render) Gzip(b bool) *Render {
+	r.opts.EnableGzip = b
+	return r
+}
+
+// TOMLIndent change the TOMLIndent for TOML on the fly
+func (r *Render) TOMLIndent(b bool) *Render {
+	r.opts.TOMLIndent = b
+	return r
+}
+
 // Charset change the Charset for response on the fly
 func (r *Render) Charset(c string) *Render {
 	r.opts.Charset = c
@@ -255,248 +674,1444 @@ func (r *Render) FuncMap(fmap template.FuncMap) *Render {
 	return r
 }
 
-// NoContent serve success but no content response
-func (r *Render) NoContent(w http.ResponseWriter) error {
-	w.WriteHeader(http.StatusNoContent)
-	return nil
+// WithRequest return a copy of the Render bound to the request, request scoped values like the CSRF token become available to templates
+func (r *Render) WithRequest(req *http.Request) *Render {
+	c := *r
+	c.req = req
+	return &c
 }
 
-// Render serve raw response where you have to build the headers, body
-func (r *Render) Render(w http.ResponseWriter, status int, v interface{}) error {
-	w.WriteHeader(status)
-	_, err := w.Write(v.([]byte))
-	return err
+// prettyParam report whether the request asks for pretty or compact output using the ?pretty, ?pretty=false,
+// ?format=pretty or ?format=compact query params, ok is false when the request does not ask for any
+func prettyParam(req *http.Request) (pretty, ok bool) {
+	q := req.URL.Query()
+	switch q.Get("format") {
+	case "pretty":
+		return true, true
+	case "compact":
+		return false, true
+	}
+	if vals, exist := q["pretty"]; exist {
+		if vals[0] == "" {
+			return true, true
+		}
+		b, err := strconv.ParseBool(vals[0])
+		return b && err == nil, true
+	}
+	return false, false
 }
 
-// String serve string content as text/plain response
-func (r *Render) String(w http.ResponseWriter, status int, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentText)
-	w.WriteHeader(status)
-	_, err := w.Write([]byte(v.(string)))
-	return err
+// WithPretty return a copy of the Render bound to the request with the JSON and XML indentation and the YAML block
+// style toggled by the pretty query params (see prettyParam), compact YAML is in flow style
+// e.g: rnd.WithPretty(r).JSON(w, http.StatusOK, v)
+func (r *Render) WithPretty(req *http.Request) *Render {
+	c := r.WithRequest(req)
+	if pretty, ok := prettyParam(req); ok {
+		c.opts.JSONIndent = pretty
+		c.opts.XMLIndent = pretty
+		c.opts.YAMLFlow = !pretty
+	}
+	return c
 }
 
-// json converts the data as bytes using json encoder
-func (r *Render) json(v interface{}) ([]byte, error) {
-	var bs []byte
-	var err error
-	if r.opts.JSONIndent {
-		bs, err = json.MarshalIndent(v, "", " ")
-	} else {
-		bs, err = json.Marshal(v)
+// setCommonHeaders set the response headers shared by every method e.g: X-Request-Id and the cookies of WithCookies
+func (r *Render) setCommonHeaders(w http.ResponseWriter) {
+	if r.opts.EchoRequestID && r.req != nil {
+		EchoRequestID(w, r.req)
 	}
-	if err != nil {
-		return bs, err
+	if r.opts.APIVersion != "" {
+		w.Header().Set(APIVersionHeader, r.opts.APIVersion)
 	}
-	if r.opts.UnEscapeHTML {
-		bs = bytes.Replace(bs, []byte("\\u003c"), []byte("<"), -1)
-		bs = bytes.Replace(bs, []byte("\\u003e"), []byte(">"), -1)
-		bs = bytes.Replace(bs, []byte("\\u0026"), []byte("&"), -1)
+	for _, c := range r.cookies {
+		r.setCookie(w, c)
 	}
-	return bs, nil
 }
 
-// JSON serve data as JSON as response
-func (r *Render) JSON(w http.ResponseWriter, status int, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentJSON)
-	w.WriteHeader(status)
+// status return the DefaultStatus when the status is 0
+func (r *Render) status(status int) int {
+	if status == 0 {
+		return r.opts.DefaultStatus
+	}
+	return status
+}
 
-	bs, err := r.json(v)
-	if err != nil {
-		return err
+// writeHeader set the common response headers and write the status, 0 means DefaultStatus
+func (r *Render) writeHeader(w http.ResponseWriter, status int) {
+	r.setCommonHeaders(w)
+	w.WriteHeader(r.status(status))
+}
+
+// writeWrapped write the bytes to w through the WriterWrappers, the wrappers implementing io.Closer are closed
+// once the bytes are written
+func (r *Render) writeWrapped(w io.Writer, bs []byte) error {
+	out := w
+	chain := make([]io.Writer, 0, len(r.opts.WriterWrappers))
+	for _, wrap := range r.opts.WriterWrappers {
+		out = wrap(out)
+		chain = append(chain, out)
 	}
-	if r.opts.JSONPrefix != "" {
-		w.Write([]byte(r.opts.JSONPrefix))
+	_, err := out.Write(bs)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if c, ok := chain[i].(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
 	}
-	_, err = w.Write(bs)
 	return err
 }
 
-// JSONP serve data as JSONP response
-func (r *Render) JSONP(w http.ResponseWriter, status int, callback string, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentJSONP)
-	w.WriteHeader(status)
-
-	bs, err := r.json(v)
-	if err != nil {
-		return err
+// wrapBody return the body passed through the WriterWrappers so that the ETag and the compression apply to the
+// wrapped body
+func (r *Render) wrapBody(bs []byte) ([]byte, error) {
+	if len(r.opts.WriterWrappers) == 0 {
+		return bs, nil
 	}
-
-	if callback == "" {
-		return errors.New("renderer: callback can not bet empty")
+	buf := r.newBuffer()
+	if err := r.writeWrapped(buf, bs); err != nil {
+		return nil, err
 	}
+	return buf.Bytes(), nil
+}
 
-	w.Write([]byte(callback + "("))
-	_, err = w.Write(bs)
-	w.Write([]byte(");"))
-
+// writeBody write the status and the body, the body is compressed with the first of Compressors and gzip (EnableGzip)
+// accepted by the bound request when the body size is at least GzipMinSize, only the headers are sent to a bound HEAD request
+func (r *Render) writeBody(w http.ResponseWriter, status int, bs []byte) error {
+	_, err := r.sendBody(w, status, bs)
 	return err
 }
 
-// XML serve data as XML response
-func (r *Render) XML(w http.ResponseWriter, status int, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentXML)
-	w.WriteHeader(status)
-	var bs []byte
-	var err error
-
-	if r.opts.XMLIndent {
-		bs, err = xml.MarshalIndent(v, "", " ")
-	} else {
-		bs, err = xml.Marshal(v)
-	}
+// sendBody write the status and the body like writeBody and report whether the body was sent, it is not for a bound
+// HEAD request and for 304 Not Modified
+func (r *Render) sendBody(w http.ResponseWriter, status int, bs []byte) (bool, error) {
+	bs, err := r.wrapBody(bs)
 	if err != nil {
-		return err
+		return false, err
 	}
-	if r.opts.XMLPrefix != "" {
-		w.Write([]byte(r.opts.XMLPrefix))
+	c := r.compressor(w, bs)
+	if r.opts.EnableETag {
+		// the ETag represents the uncompressed body and is marked with the encoding
+		encoding := ""
+		if c != nil {
+			encoding = c.Encoding()
+		}
+		etag := etagOf(bs, encoding)
+		w.Header().Set(ETag, etag)
+		if r.status(status) == http.StatusOK && r.notModified(etag) {
+			r.writeHeader(w, http.StatusNotModified)
+			return false, nil
+		}
+	}
+	if r.req != nil && r.req.Method == http.MethodHead {
+		// HEAD responds with the headers of the GET response without the body
+		if c != nil {
+			w.Header().Set(ContentEncoding, c.Encoding())
+			w.Header().Del(ContentLength)
+		} else {
+			w.Header().Set(ContentLength, strconv.Itoa(len(bs)))
+		}
+		r.writeHeader(w, status)
+		return false, nil
+	}
+	if c != nil {
+		err = r.writeCompressed(w, status, bs, c)
+		return err == nil, err
 	}
+	r.writeHeader(w, status)
 	_, err = w.Write(bs)
-	return err
+	return err == nil, err
 }
 
-// YAML serve data as YAML response
-func (r *Render) YAML(w http.ResponseWriter, status int, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentYAML)
-	w.WriteHeader(status)
+// csrfToken return the CSRF token stored in the bound request context
+func (r *Render) csrfToken() string {
+	if r.req == nil {
+		return ""
+	}
+	token, _ := r.req.Context().Value(r.opts.CSRFContextKey).(string)
+	return token
+}
 
-	bs, err := yaml.Marshal(v)
-	if err != nil {
-		return err
+// templateData merge the request scoped values and the GlobalData as Global into template data when the data is a map or nil
+func (r *Render) templateData(v interface{}) interface{} {
+	token := r.csrfToken()
+	if token == "" && len(r.opts.GlobalData) == 0 {
+		return v
 	}
-	_, err = w.Write(bs)
-	return err
+	data := M{}
+	switch m := v.(type) {
+	case nil:
+	case M:
+		for k, val := range m {
+			data[k] = val
+		}
+	case map[string]interface{}:
+		for k, val := range m {
+			data[k] = val
+		}
+	default:
+		return v
+	}
+	if token != "" {
+		data[string(CSRFTokenKey)] = token
+	}
+	if len(r.opts.GlobalData) > 0 {
+		data[globalDataKey] = r.opts.GlobalData
+	}
+	return data
 }
 
-// HTMLString render string as html. Note: You must provide trusted html when using this method
-func (r *Render) HTMLString(w http.ResponseWriter, status int, html string) error {
-	w.Header().Set(ContentType, r.opts.ContentHTML)
-	w.WriteHeader(status)
-	out := template.HTML(html)
-	_, err := w.Write([]byte(out))
-	return err
+// SetGlobal set the value of the key in GlobalData, it is meant to be called while setting up the renderer
+// as the data is shared by the concurrent renders
+func (r *Render) SetGlobal(key string, value interface{}) *Render {
+	if r.opts.GlobalData == nil {
+		r.opts.GlobalData = make(map[string]interface{})
+	}
+	r.opts.GlobalData[key] = value
+	return r
 }
 
-// HTML render html from template.Glob patterns and execute template by name. See README.md for detail example.
-func (r *Render) HTML(w http.ResponseWriter, status int, name string, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentHTML)
-	w.WriteHeader(status)
+// newBuffer return an empty buffer pre-allocated with BufferHint bytes
+func (r *Render) newBuffer() *bytes.Buffer {
+	if r.opts.BufferHint <= 0 {
+		return new(bytes.Buffer)
+	}
+	return bytes.NewBuffer(make([]byte, 0, r.opts.BufferHint))
+}
 
-	if name == "" {
-		return errors.New("renderer: template name not exist")
+// maxPooledBuffer is the capacity above which a buffer is dropped instead of being returned to bufferPool
+const maxPooledBuffer = 1 << 20
+
+// bufferPool holds the buffers the html is rendered into
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// getBuffer return an empty buffer from the pool grown to BufferHint bytes, it must be released by putBuffer
+func (r *Render) getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	if r.opts.BufferHint > 0 {
+		buf.Grow(r.opts.BufferHint)
 	}
+	return buf
+}
 
-	if r.opts.Debug {
-		r.parseGlob()
+// putBuffer reset the buffer and return it to the pool, the bytes of the buffer must not be used afterwards
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
 	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
 
-	buf := new(bytes.Buffer)
-	defer buf.Reset()
+// ErrTemplateOutputTooLarge is returned when the template output exceeds MaxTemplateOutput
+var ErrTemplateOutputTooLarge = errors.New("renderer: template output exceeds MaxTemplateOutput")
 
-	if err := r.globTemplates.ExecuteTemplate(buf, name, v); err != nil {
-		return err
-	}
-	_, err := w.Write(buf.Bytes())
-	return err
+// outputLimiter describes a writer failing once more than max bytes are written
+type outputLimiter struct {
+	w   io.Writer
+	n   int
+	max int
 }
 
-// Template build html from template and serve html content as response. See README.md for detail example.
-func (r *Render) Template(w http.ResponseWriter, status int, tpls []string, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentHTML)
-	w.WriteHeader(status)
+// Write write the bytes unless the limit is exceeded
+func (l *outputLimiter) Write(p []byte) (int, error) {
+	if l.n+len(p) > l.max {
+		return 0, ErrTemplateOutputTooLarge
+	}
+	l.n += len(p)
+	return l.w.Write(p)
+}
 
-	tmain := template.New(filepath.Base(tpls[0]))
-	tmain.Delims(r.opts.LeftDelim, r.opts.RightDelim)
-	for _, fm := range r.opts.FuncMap {
-		tmain.Funcs(fm)
+// limitWriter return the writer limited by MaxTemplateOutput
+func (r *Render) limitWriter(w io.Writer) io.Writer {
+	if r.opts.MaxTemplateOutput <= 0 {
+		return w
 	}
-	t := template.Must(tmain.ParseFiles(tpls...))
+	return &outputLimiter{w: w, max: r.opts.MaxTemplateOutput}
+}
 
-	buf := new(bytes.Buffer)
-	defer buf.Reset()
+// renderHTML execute the template into buf, the output is minified and cached when CacheRenderedHTML is set, the key
+// is not empty, no CSRF token is bound and Debug is off. The returned bytes may belong to buf
+func (r *Render) renderHTML(buf *bytes.Buffer, key string, v interface{}, exec func(io.Writer, interface{}) error) ([]byte, error) {
+	data := r.templateData(v)
+	// the output carrying the CSRF token of the request is never shared
+	if r.htmlCache == nil || key == "" || r.opts.Debug || r.csrfToken() != "" {
+		if err := exec(r.limitWriter(buf), data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
 
-	if err := t.Execute(buf, v); err != nil {
-		return err
+	if bs, ok := r.htmlCache.get(key); ok {
+		return bs, nil
 	}
-	_, err := w.Write(buf.Bytes())
-	return err
+	if err := exec(r.limitWriter(buf), data); err != nil {
+		return nil, err
+	}
+	bs := minifyHTML(buf.Bytes())
+	r.htmlCache.set(key, bs)
+	return bs, nil
 }
 
-// View build html from template directory and serve html content as response. See README.md for detail example.
-func (r *Render) View(w http.ResponseWriter, status int, name string, v interface{}) error {
-	w.Header().Set(ContentType, r.opts.ContentHTML)
-	w.WriteHeader(status)
+// NoContent serve success but no content response
+func (r *Render) NoContent(w http.ResponseWriter) error {
+	r.writeHeader(w, http.StatusNoContent)
+	return nil
+}
 
-	buf := new(bytes.Buffer)
-	defer buf.Reset()
+// Status serve the canonical text of the status code as text/plain response e.g: 404 Not Found
+func (r *Render) Status(w http.ResponseWriter, code int) error {
+	return r.String(w, code, http.StatusText(code))
+}
 
-	if r.opts.Debug {
-		r.parseTemplates()
-	}
+// Render serve raw response where you have to build the headers, body
+func (r *Render) Render(w http.ResponseWriter, status int, v interface{}) error {
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, v.([]byte))
+}
 
-	name += r.opts.TemplateExtension
-	tmpl, ok := r.templates[name]
-	if !ok {
-		return fmt.Errorf("renderer: template %s does not exist", name)
+// String serve string content as text/plain response
+func (r *Render) String(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set(ContentType, r.opts.ContentText)
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, []byte(v.(string)))
+}
+
+// StringWithType serve string content with the given content type e.g: text/css, text/javascript
+func (r *Render) StringWithType(w http.ResponseWriter, status int, contentType, s string) error {
+	w.Header().Set(ContentType, r.withCharset(contentType))
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, []byte(s))
+}
+
+// propertiesEscaper escape the properties values so every pair stays on a single line
+var propertiesEscaper = strings.NewReplacer(`\`, `\\`, "\r", `\r`, "\n", `\n`)
+
+// Properties serve map as key=value lines sorted by key as text/plain response, e.g: .env or .properties files
+func (r *Render) Properties(w http.ResponseWriter, status int, m map[string]string) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	if err := tmpl.Execute(buf, v); err != nil {
-		return err
+	buf := r.newBuffer()
+	for _, k := range keys {
+		buf.WriteString(k + "=" + propertiesEscaper.Replace(m[k]) + "\n")
 	}
 
-	_, err := w.Write(buf.Bytes())
-	return err
+	w.Header().Set(ContentType, r.opts.ContentText)
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, buf.Bytes())
 }
 
-// Binary serve file as application/octet-stream response; you may add ContentDisposition by your own.
-func (r *Render) Binary(w http.ResponseWriter, status int, reader io.Reader, filename string, inline bool) error {
-	if inline {
-		w.Header().Set(ContentDisposition, fmt.Sprintf("%s; filename=%s", contentDispositionInline, filename))
-	} else {
-		w.Header().Set(ContentDisposition, fmt.Sprintf("%s; filename=%s", contentDispositionAttachment, filename))
+// checkJSONShape validate the data against ForceArray and ForceObject
+func (r *Render) checkJSONShape(v interface{}) error {
+	if !r.opts.ForceArray && !r.opts.ForceObject {
+		return nil
 	}
-	w.Header().Set(ContentType, r.opts.ContentBinary)
-	w.WriteHeader(status)
-	bs, err := ioutil.ReadAll(reader)
-	if err != nil {
-		return err
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	kind := rv.Kind()
+	if r.opts.ForceArray && kind != reflect.Slice && kind != reflect.Array {
+		return fmt.Errorf("renderer: json data must be an array, got %s", kind)
 	}
+	if r.opts.ForceObject && kind != reflect.Struct && kind != reflect.Map {
+		return fmt.Errorf("renderer: json data must be an object, got %s", kind)
+	}
+	return nil
+}
 
-	_, err = w.Write(bs)
-	return err
+// jsonField describes a top level field added to the JSON object e.g: the type tag
+type jsonField struct {
+	key   string
+	value interface{}
 }
 
-// File serve file as response from io.Reader
-func (r *Render) File(w http.ResponseWriter, status int, reader io.Reader, filename string, inline bool) error {
-	bs, err := ioutil.ReadAll(reader)
-	if err != nil {
-		return err
+// withJSONFields describes a data encoded by marshal with the extra top level fields
+type withJSONFields struct {
+	fields  []jsonField
+	v       interface{}
+	marshal func(v interface{}) ([]byte, error)
+}
+
+// MarshalJSON add the fields in order before the fields of the encoded object, a field already present in the object
+// is kept as it is and data other than an object is encoded without the fields
+func (f withJSONFields) MarshalJSON() ([]byte, error) {
+	bs, err := f.marshal(f.v)
+	if err != nil || len(bs) < 2 || bs[0] != '{' {
+		return bs, err
+	}
+	var existing map[string]json.RawMessage
+	if err = json.Unmarshal(bs, &existing); err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBufferString("{")
+	for _, field := range f.fields {
+		if _, ok := existing[field.key]; ok {
+			continue
+		}
+		key, _ := json.Marshal(field.key)
+		val, err := f.marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+		buf.WriteByte(',')
+	}
+	if len(existing) == 0 {
+		buf.Truncate(buf.Len() - 1)
 	}
+	buf.Write(bs[1:])
+	return buf.Bytes(), nil
+}
 
-	// set headers
-	mime := http.DetectContentType(bs)
-	if inline {
-		w.Header().Set(ContentDisposition, fmt.Sprintf("%s; filename=%s", contentDispositionInline, filename))
-	} else {
-		w.Header().Set(ContentDisposition, fmt.Sprintf("%s; filename=%s", contentDispositionAttachment, filename))
+// structName return the Go type name of the struct data, other data returns empty string
+func structName(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
 	}
-	w.Header().Set(ContentType, mime)
-	w.WriteHeader(status)
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	return rv.Type().Name()
+}
 
-	_, err = w.Write(bs)
-	return err
+// marshalJSON encode the data compact with the JSONMarshaler, encoding/json is used when it is not set
+func (r *Render) marshalJSON(v interface{}) ([]byte, error) {
+	if r.opts.JSONMarshaler != nil {
+		return r.opts.JSONMarshaler(v)
+	}
+	return json.Marshal(v)
 }
 
-// file serve file as response
-func (r *Render) file(w http.ResponseWriter, status int, fpath, name, contentDisposition string) error {
+// json converts the data as bytes using json encoder, the fields are added to the top level object
+func (r *Render) json(v interface{}, fields ...jsonField) ([]byte, error) {
 	var bs []byte
 	var err error
-	bs, err = ioutil.ReadFile(fpath)
-	if err != nil {
-		return err
+	if err = r.checkJSONShape(v); err != nil {
+		return bs, err
+	}
+	if r.opts.TypeTag {
+		if name := structName(v); name != "" {
+			fields = append(fields, jsonField{key: "type", value: name})
+		}
+	}
+	if r.opts.TimeFormat != "" || r.opts.Deterministic {
+		v = normalizeJSON(v, r.opts.TimeFormat, r.opts.Deterministic, r.opts.Deterministic)
+	}
+	if len(fields) > 0 {
+		v = withJSONFields{fields: fields, v: v, marshal: r.marshalJSON}
+	}
+	if r.opts.JSONMarshaler == nil && r.opts.JSONIndent && r.opts.JSONMaxIndentDepth <= 0 {
+		bs, err = json.MarshalIndent(v, "", r.opts.JSONIndentValue)
+	} else {
+		bs, err = r.marshalJSON(v)
+	}
+	if err != nil {
+		return bs, err
+	}
+	if r.opts.Debug && r.opts.JSONSchemaValidator != nil && r.opts.JSONSchema != nil {
+		if err = r.opts.JSONSchemaValidator(r.opts.JSONSchema, bs); err != nil {
+			return nil, fmt.Errorf("renderer: json schema validation failed: %s", err.Error())
+		}
+	}
+	if r.opts.JSONIndent && r.opts.JSONMaxIndentDepth > 0 {
+		bs = indentJSON(bs, r.opts.JSONIndentValue, r.opts.JSONMaxIndentDepth)
+	}
+	if r.opts.UnEscapeHTML {
+		bs = bytes.Replace(bs, []byte("\\u003c"), []byte("<"), -1)
+		bs = bytes.Replace(bs, []byte("\\u003e"), []byte(">"), -1)
+		bs = bytes.Replace(bs, []byte("\\u0026"), []byte("&"), -1)
+	}
+	if r.opts.ASCIIOnlyJSON {
+		bs = escapeNonASCII(bs)
+	}
+	return bs, nil
+}
+
+// indentJSON indent the compact JSON up to maxDepth levels, the deeper levels are kept compact
+func indentJSON(src []byte, indent string, maxDepth int) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(src)*2))
+	newline := func(depth int) {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Repeat(indent, depth))
+	}
+
+	depth := 0
+	inString := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if inString {
+			buf.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				buf.WriteByte(src[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			buf.WriteByte(c)
+		case '{', '[':
+			buf.WriteByte(c)
+			depth++
+			if i+1 < len(src) && (src[i+1] == '}' || src[i+1] == ']') {
+				i++
+				buf.WriteByte(src[i])
+				depth--
+			} else if depth <= maxDepth {
+				newline(depth)
+			}
+		case '}', ']':
+			depth--
+			if depth < maxDepth {
+				newline(depth)
+			}
+			buf.WriteByte(c)
+		case ',':
+			buf.WriteByte(c)
+			if depth <= maxDepth {
+				newline(depth)
+			}
+		case ':':
+			buf.WriteByte(c)
+			if depth <= maxDepth {
+				buf.WriteByte(' ')
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.Bytes()
+}
+
+// escapeNonASCII escape the non-ASCII runes as \uXXXX, runes outside the BMP are escaped as surrogate pairs
+func escapeNonASCII(bs []byte) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(bs)))
+	for len(bs) > 0 {
+		c, size := utf8.DecodeRune(bs)
+		bs = bs[size:]
+		if c < utf8.RuneSelf {
+			buf.WriteByte(byte(c))
+			continue
+		}
+		if r1, r2 := utf16.EncodeRune(c); r1 != unicode.ReplacementChar {
+			fmt.Fprintf(buf, "\\u%04x\\u%04x", r1, r2)
+			continue
+		}
+		fmt.Fprintf(buf, "\\u%04x", c)
+	}
+	return buf.Bytes()
+}
+
+// statusContentType return the Content-Type mapped to the status or its class by StatusContentType, the
+// contentType is returned when the status is not mapped
+func (r *Render) statusContentType(status int, contentType string) string {
+	status = r.status(status)
+	if ct, ok := r.opts.StatusContentType[status]; ok {
+		return r.withCharset(ct)
+	}
+	if ct, ok := r.opts.StatusContentType[status/100]; ok {
+		return r.withCharset(ct)
+	}
+	return contentType
+}
+
+// JSON serve data as JSON as response
+func (r *Render) JSON(w http.ResponseWriter, status int, v interface{}) error {
+	if r.opts.JSONEnvelope {
+		v = envelope(v, nil, nil)
+	}
+	var fields []jsonField
+	if r.opts.APIVersion != "" && r.opts.APIVersionField {
+		fields = append(fields, jsonField{key: "version", value: r.opts.APIVersion})
+	}
+	bs, err := r.json(v, fields...)
+	if err != nil {
+		return err
+	}
+	payload := bs
+	if r.opts.JSONPrefix != "" {
+		bs = append([]byte(r.opts.JSONPrefix), bs...)
+	}
+	w.Header().Set(ContentType, r.statusContentType(status, r.opts.ContentJSON))
+	sent, err := r.sendBody(w, status, bs)
+	if err != nil {
+		return err
+	}
+	if sent && r.opts.PostRenderWebhook != "" {
+		go r.postWebhook(payload)
+	}
+	return nil
+}
+
+// JSONPretty serve data as indented JSON response regardless of the JSONIndent option
+func (r *Render) JSONPretty(w http.ResponseWriter, status int, v interface{}) error {
+	c := *r
+	c.opts.JSONIndent = true
+	return c.JSON(w, status, v)
+}
+
+// JSONFields serve only the named top level fields of data as JSON response e.g: for ?fields=name,age sparse
+// fieldsets, the fields are matched by their JSON name. All the fields are served when fields is empty
+func (r *Render) JSONFields(w http.ResponseWriter, status int, v interface{}, fields []string) error {
+	if len(fields) == 0 {
+		return r.JSON(w, status, v)
+	}
+	bs, err := r.marshalJSON(v)
+	if err != nil {
+		return err
+	}
+	var obj map[string]json.RawMessage
+	if err = json.Unmarshal(bs, &obj); err != nil {
+		return fmt.Errorf("renderer: json fields require an object, %s", err.Error())
+	}
+	filtered := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		if raw, ok := obj[f]; ok {
+			filtered[f] = raw
+		}
+	}
+	return r.JSON(w, status, filtered)
+}
+
+// envelope wrap the data or the error in the JSON envelope e.g: {"data":{"id":1},"error":null,"meta":{"total":1}},
+// meta is omitted when it is nil
+func envelope(data, err, meta interface{}) M {
+	m := M{"data": data, "error": err}
+	if meta != nil {
+		m["meta"] = meta
+	}
+	return m
+}
+
+// unwrappedJSON serve data as JSON response without the envelope of the JSONEnvelope option
+func (r *Render) unwrappedJSON(w http.ResponseWriter, status int, v interface{}) error {
+	c := *r
+	c.opts.JSONEnvelope = false
+	return c.JSON(w, status, v)
+}
+
+// JSONWrapped serve data and meta wrapped in the JSON envelope as response, regardless of the JSONEnvelope option
+func (r *Render) JSONWrapped(w http.ResponseWriter, status int, data, meta interface{}) error {
+	return r.unwrappedJSON(w, status, envelope(data, nil, meta))
+}
+
+// JSONBlob serve the already encoded JSON bytes as response without marshalling them again
+func (r *Render) JSONBlob(w http.ResponseWriter, status int, b []byte) error {
+	w.Header().Set(ContentType, r.statusContentType(status, r.opts.ContentJSON))
+	return r.writeBody(w, status, b)
+}
+
+// JSONReader serve the already encoded JSON read from the reader as response without marshalling it again
+func (r *Render) JSONReader(w http.ResponseWriter, status int, reader io.Reader) error {
+	defer r.closeReader(reader)
+	bs, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return r.JSONBlob(w, status, bs)
+}
+
+// Error serve the error message as JSON response, the status code and text are included when
+// IncludeStatusCode and IncludeStatusText are set e.g: {"error":"invalid id","status":400,"status_text":"Bad Request"}.
+// With JSONEnvelope the message fills the envelope error and the status is sent in its meta
+func (r *Render) Error(w http.ResponseWriter, status int, msg string) error {
+	status = r.status(status)
+	body := errorBody{Error: msg}
+	if r.opts.IncludeStatusCode {
+		body.Status = status
+	}
+	if r.opts.IncludeStatusText {
+		body.StatusText = http.StatusText(status)
+	}
+	if !r.opts.JSONEnvelope {
+		return r.JSON(w, status, body)
+	}
+	meta := M{}
+	if r.opts.IncludeStatusCode {
+		meta["status"] = body.Status
+	}
+	if r.opts.IncludeStatusText {
+		meta["status_text"] = body.StatusText
+	}
+	if len(meta) == 0 {
+		return r.unwrappedJSON(w, status, envelope(nil, msg, nil))
+	}
+	return r.unwrappedJSON(w, status, envelope(nil, msg, meta))
+}
+
+// healthBody describes the JSON body of Health
+type healthBody struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Health serve the result of the checks as JSON e.g: {"status":"fail","checks":{"cache":"ok","db":"timeout"}},
+// the status is sent when every check passes otherwise 503 Service Unavailable is sent
+func (r *Render) Health(w http.ResponseWriter, status int, checks map[string]error) error {
+	body := healthBody{Status: "ok", Checks: make(map[string]string, len(checks))}
+	for name, err := range checks {
+		if err != nil {
+			body.Status = "fail"
+			body.Checks[name] = err.Error()
+			continue
+		}
+		body.Checks[name] = "ok"
+	}
+	if body.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	return r.unwrappedJSON(w, status, body)
+}
+
+// jsonpCallback matches the JavaScript identifiers allowed as JSONP callback including dotted namespaces e.g: ns.cb
+var jsonpCallback = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+
+// JSONP serve data as JSONP response
+func (r *Render) JSONP(w http.ResponseWriter, status int, callback string, v interface{}) error {
+	if callback == "" {
+		return errors.New("renderer: callback can not bet empty")
+	}
+	if !jsonpCallback.MatchString(callback) {
+		return fmt.Errorf("renderer: invalid callback %q", callback)
+	}
+	bs, err := r.json(v)
+	if err != nil {
+		return err
+	}
+
+	out := append([]byte(callback+"("), bs...)
+	w.Header().Set(ContentType, r.opts.ContentJSONP)
+	return r.writeBody(w, status, append(out, ");"...))
+}
+
+// XML serve data as XML response
+func (r *Render) XML(w http.ResponseWriter, status int, v interface{}) error {
+	bs, err := r.xml(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(ContentType, r.opts.ContentXML)
+	return r.writeBody(w, status, bs)
+}
+
+// xml converts the data as bytes using xml encoder prefixed by XMLPrefix
+func (r *Render) xml(v interface{}) ([]byte, error) {
+	var bs []byte
+	var err error
+
+	if r.opts.XMLMapRoot != "" && reflect.ValueOf(v).Kind() == reflect.Map {
+		v = XMLRoot(r.opts.XMLMapRoot, v)
+	}
+	if r.opts.XMLIndent {
+		bs, err = xml.MarshalIndent(v, "", r.opts.XMLIndentValue)
+	} else {
+		bs, err = xml.Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.opts.XMLPrefix != "" {
+		bs = append([]byte(r.opts.XMLPrefix), bs...)
+	}
+	return bs, nil
+}
+
+// XMLMap serve map as XML response under the root element, the child elements are sorted by key
+func (r *Render) XMLMap(w http.ResponseWriter, status int, root string, m map[string]interface{}) error {
+	buf := r.newBuffer()
+	enc := xml.NewEncoder(buf)
+	if r.opts.XMLIndent {
+		enc.Indent("", r.opts.XMLIndentValue)
+	}
+	if err := encodeXMLMap(enc, root, m); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	bs := buf.Bytes()
+	if r.opts.XMLPrefix != "" {
+		bs = append([]byte(r.opts.XMLPrefix), bs...)
+	}
+	w.Header().Set(ContentType, r.opts.ContentXML)
+	return r.writeBody(w, status, bs)
+}
+
+// encodeXMLMap encode the map as element name, the keys are encoded in sorted order and nested maps recursively
+func encodeXMLMap(enc *xml.Encoder, name string, m map[string]interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := encodeXMLValue(enc, k, m[k]); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// encodeXMLValue encode the value as element name, slices are encoded as repeated elements
+func encodeXMLValue(enc *xml.Encoder, name string, v interface{}) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return encodeXMLMap(enc, name, val)
+	case M:
+		return encodeXMLMap(enc, name, val)
+	case []interface{}:
+		for _, item := range val {
+			if err := encodeXMLValue(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+// xmlRoot describes a data encoded under a custom root element
+type xmlRoot struct {
+	start xml.StartElement
+	v     interface{}
+}
+
+// XMLRoot wrap the data so that XML encodes it under the root element with the attributes e.g: a namespace,
+// the entries of a map are encoded as the child elements sorted by key
+// e.g: rnd.XML(w, http.StatusOK, renderer.XMLRoot("user", m, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: ns}))
+func XMLRoot(name string, v interface{}, attrs ...xml.Attr) interface{} {
+	return xmlRoot{start: xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}, v: v}
+}
+
+// MarshalXML implements the xml.Marshaler interface
+func (x xmlRoot) MarshalXML(enc *xml.Encoder, _ xml.StartElement) error {
+	var m map[string]interface{}
+	switch val := x.v.(type) {
+	case map[string]interface{}:
+		m = val
+	case M:
+		m = val
+	default:
+		rv := reflect.ValueOf(x.v)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return enc.EncodeElement(x.v, x.start)
+		}
+		m = make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			m[k.String()] = rv.MapIndex(k).Interface()
+		}
+	}
+
+	if err := enc.EncodeToken(x.start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := encodeXMLValue(enc, k, m[k]); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(x.start.End())
+}
+
+// YAML serve data as YAML response, the keys of a map are sorted and the items of a YAMLMapSlice keep their order
+func (r *Render) YAML(w http.ResponseWriter, status int, v interface{}) error {
+	bs, err := r.yaml(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(ContentType, r.opts.ContentYAML)
+	return r.writeBody(w, status, bs)
+}
+
+// withCharsetOverride return a copy of the Render whose content types carry the charset, the shared options are not mutated
+func (r *Render) withCharsetOverride(charset string) *Render {
+	c := *r
+	c.opts.Charset = charset
+	c.opts.DisableCharset = false
+	c.opts.ContentJSON = c.withCharset(ContentJSON)
+	c.opts.ContentXML = c.withCharset(ContentXML)
+	c.opts.ContentYAML = c.withCharset(ContentYAML)
+	return &c
+}
+
+// JSONWithCharset serve data as JSON as response with the charset for this call only e.g: ISO-8859-1
+func (r *Render) JSONWithCharset(w http.ResponseWriter, status int, v interface{}, charset string) error {
+	return r.withCharsetOverride(charset).JSON(w, status, v)
+}
+
+// XMLWithCharset serve data as XML response with the charset for this call only e.g: ISO-8859-1
+func (r *Render) XMLWithCharset(w http.ResponseWriter, status int, v interface{}, charset string) error {
+	return r.withCharsetOverride(charset).XML(w, status, v)
+}
+
+// YAMLWithCharset serve data as YAML response with the charset for this call only e.g: ISO-8859-1
+func (r *Render) YAMLWithCharset(w http.ResponseWriter, status int, v interface{}, charset string) error {
+	return r.withCharsetOverride(charset).YAML(w, status, v)
+}
+
+type (
+	// YAMLMapSlice describes an ordered map, YAML encode its items in the given order instead of sorting the keys
+	YAMLMapSlice = yaml.MapSlice
+	// YAMLMapItem describes an item of YAMLMapSlice
+	YAMLMapItem = yaml.MapItem
+)
+
+// marshalYAML marshal the data as YAML, the panic of the encoder on unsupported types e.g: func is returned as error
+func marshalYAML(v interface{}) (bs []byte, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("renderer: yaml: %v", rec)
+		}
+	}()
+	return yaml.Marshal(v)
+}
+
+// yaml converts the data as bytes using yaml encoder, in flow style when YAMLFlow is set
+func (r *Render) yaml(v interface{}) ([]byte, error) {
+	bs, err := marshalYAML(v)
+	if err != nil || !r.opts.YAMLFlow {
+		return bs, err
+	}
+	// the data is decoded back wrapped in a map so that the nested maps become YAMLMapSlice keeping the key order
+	var doc yaml.MapSlice
+	if err = yaml.Unmarshal(append([]byte("v:\n"), indentYAML(bs)...), &doc); err != nil {
+		return nil, fmt.Errorf("renderer: yaml: %s", err.Error())
+	}
+	buf := r.newBuffer()
+	if err = writeFlowYAML(buf, doc[0].Value); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// indentYAML indent every line of the YAML document by two spaces
+func indentYAML(bs []byte) []byte {
+	lines := bytes.SplitAfter(bs, []byte("\n"))
+	out := make([]byte, 0, len(bs)+2*len(lines))
+	for _, line := range lines {
+		if len(line) > 0 {
+			out = append(out, "  "...)
+			out = append(out, line...)
+		}
+	}
+	return out
+}
+
+// writeFlowYAML write the decoded YAML value in flow style, the strings are double quoted like JSON strings
+// which YAML accepts and the other scalars are written as the yaml encoder writes them
+func writeFlowYAML(buf *bytes.Buffer, v interface{}) error {
+	switch x := v.(type) {
+	case yaml.MapSlice:
+		buf.WriteByte('{')
+		for i, item := range x {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := writeFlowYAML(buf, item.Key); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			if err := writeFlowYAML(buf, item.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range x {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := writeFlowYAML(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case string:
+		bs, _ := json.Marshal(x)
+		buf.Write(bs)
+	default:
+		bs, err := marshalYAML(x)
+		if err != nil {
+			return err
+		}
+		buf.Write(bytes.TrimSuffix(bs, []byte("\n")))
+	}
+	return nil
+}
+
+// TOML serve data as TOML as response
+func (r *Render) TOML(w http.ResponseWriter, status int, v interface{}) error {
+	bs, err := r.toml(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(ContentType, r.opts.ContentTOML)
+	return r.writeBody(w, status, bs)
+}
+
+// toml converts the data as bytes using toml encoder
+func (r *Render) toml(v interface{}) ([]byte, error) {
+	buf := r.newBuffer()
+	enc := toml.NewEncoder(buf)
+	enc.Indent = ""
+	if r.opts.TOMLIndent {
+		enc.Indent = " "
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG serve svg image as image/svg+xml response, the content type is sent without charset
+func (r *Render) SVG(w http.ResponseWriter, status int, svg []byte) error {
+	w.Header().Set(ContentType, ContentSVG)
+	r.writeHeader(w, status)
+	if r.opts.SVGDeclaration && !bytes.HasPrefix(bytes.TrimSpace(svg), []byte("<?xml")) {
+		svg = append([]byte(defaultSVGDeclaration), svg...)
 	}
-	buf := bytes.NewBuffer(bs)
+	return r.writeWrapped(w, svg)
+}
+
+// dataURI return the base64 encoded data URI of the data e.g: data:image/png;base64,iVBORw0KGgo=
+func dataURI(data []byte, mimeType string) template.URL {
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// DataURI serve the base64 encoded data URI of the data as text/plain response, to embed an asset in a template
+// use the dataURI template func e.g: <img src="{{dataURI .logo "image/png"}}">
+func (r *Render) DataURI(w http.ResponseWriter, status int, data []byte, mimeType string) error {
+	w.Header().Set(ContentType, r.opts.ContentText)
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, []byte(dataURI(data, mimeType)))
+}
+
+// metaCharset matches the charset declared by the meta tag of html e.g: <meta charset="ISO-8859-1">
+var metaCharset = regexp.MustCompile(`(?i)<meta\s[^>]*charset\s*=\s*["']?([\w.:-]+)`)
+
+// detectHTMLCharset set the charset of the Content-Type to the charset declared by the meta tag of the html
+// when DetectHTMLCharset is set, only the first 1024 bytes are inspected like browsers do
+func (r *Render) detectHTMLCharset(w http.ResponseWriter, bs []byte) {
+	if !r.opts.DetectHTMLCharset {
+		return
+	}
+	if len(bs) > 1024 {
+		bs = bs[:1024]
+	}
+	if m := metaCharset.FindSubmatch(bs); m != nil {
+		w.Header().Set(ContentType, ContentHTML+"; charset="+string(m[1]))
+	}
+}
+
+// setHTMLHeaders set the Content-Type and the Content-Security-Policy of the HTML responses
+func (r *Render) setHTMLHeaders(w http.ResponseWriter) {
+	w.Header().Set(ContentType, r.opts.ContentHTML)
+	if r.opts.CSP == "" {
+		return
+	}
+	if r.opts.CSPReportOnly {
+		w.Header().Set(ContentSecurityPolicyReportOnly, r.opts.CSP)
+		return
+	}
+	w.Header().Set(ContentSecurityPolicy, r.opts.CSP)
+}
+
+// InternalServerError is an ErrorHandler writing 500 Internal Server Error as text/plain response
+func InternalServerError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// renderError report the rendering error of HTML, View and Template to the Logger and hand it to the ErrorHandler
+// before anything is written, the error is returned to the caller as well
+func (r *Render) renderError(w http.ResponseWriter, err error) error {
+	var perr parseError
+	if r.opts.Logger != nil && !errors.As(err, &perr) {
+		r.opts.Logger.Printf("renderer: %v", err)
+	}
+	w.Header().Del(ContentType)
+	r.opts.ErrorHandler(w, r.req, err)
+	return err
+}
+
+// HTMLString render string as html. Note: You must provide trusted html when using this method
+func (r *Render) HTMLString(w http.ResponseWriter, status int, html string) error {
+	r.setHTMLHeaders(w)
+	r.writeHeader(w, status)
+	out := template.HTML(html)
+	return r.writeWrapped(w, []byte(out))
+}
+
+// HTML render html from template.Glob patterns and execute template by name. See README.md for detail example.
+func (r *Render) HTML(w http.ResponseWriter, status int, name string, v interface{}, opts ...RenderOption) error {
+	r.setHTMLHeaders(w)
 
+	buf := r.getBuffer()
+	defer putBuffer(buf)
+	bs, err := r.renderGlob(buf, name, v, opts)
+	if err != nil {
+		return r.renderError(w, err)
+	}
+	if err = r.snapshot(name, bs); err != nil {
+		r.logf("renderer: %v", err)
+	}
+	r.detectHTMLCharset(w, bs)
+	return r.writeBody(w, status, bs)
+}
+
+// Partial serve a single named block e.g: {{define "header"}} of the ParseGlobPattern set as html response without
+// the page around it, useful for the AJAX endpoints
+func (r *Render) Partial(w http.ResponseWriter, status int, name string, data interface{}) error {
+	buf := r.getBuffer()
+	defer putBuffer(buf)
+	bs, err := r.renderGlob(buf, name, data, nil)
+	if err != nil {
+		return err
+	}
+	r.setHTMLHeaders(w)
+	return r.writeBody(w, status, bs)
+}
+
+// renderGlob execute the template parsed by ParseGlobPattern by name
+func (r *Render) renderGlob(buf *bytes.Buffer, name string, v interface{}, opts []RenderOption) ([]byte, error) {
+	if name == "" {
+		return nil, errors.New("renderer: template name not exist")
+	}
+
+	master, exec, err := r.globSets()
+	if err != nil {
+		return nil, err
+	}
+	if exec == nil {
+		return nil, errors.New("renderer: no template parsed, set the ParseGlobPattern or ParseGlobPatterns option")
+	}
+	if exec.Lookup(name) == nil {
+		return nil, fmt.Errorf("renderer: template %s is not defined", name)
+	}
+
+	cfg := newRenderConfig(opts)
+	tmpl, err := r.executable(cfg, master, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.renderHTML(buf, cfg.cacheKeyOf("html:"+name, v), v, func(w io.Writer, data interface{}) error {
+		return tmpl.ExecuteTemplate(w, name, data)
+	})
+}
+
+// Template build html from template and serve html content as response. See README.md for detail example.
+func (r *Render) Template(w http.ResponseWriter, status int, tpls []string, v interface{}, opts ...RenderOption) error {
+	r.setHTMLHeaders(w)
+
+	t, err := r.templateSet(tpls, newRenderConfig(opts))
+	if err != nil {
+		return r.renderError(w, err)
+	}
+	buf := r.getBuffer()
+	defer putBuffer(buf)
+	if err = t.Execute(r.limitWriter(buf), r.templateData(v)); err != nil {
+		return r.renderError(w, err)
+	}
+	r.detectHTMLCharset(w, buf.Bytes())
+	return r.writeBody(w, status, buf.Bytes())
+}
+
+// templateSet return the template set of the files, the set is served from the TemplateCacheSize cache
+// unless per call funcs are set or Debug is on
+func (r *Render) templateSet(tpls []string, c *renderConfig) (*template.Template, error) {
+	cacheable := r.templateSets != nil && len(c.funcs) == 0 && !r.opts.Debug
+	key := strings.Join(tpls, "\x00")
+	if cacheable {
+		if t, ok := r.templateSets.get(key); ok {
+			return t, nil
+		}
+	}
+
+	tmain := template.New(filepath.Base(tpls[0]))
+	tmain.Delims(r.opts.LeftDelim, r.opts.RightDelim)
+	tmain.Funcs(r.templateFuncs())
+	for _, fm := range r.opts.FuncMap {
+		tmain.Funcs(fm)
+	}
+	tmain.Funcs(c.funcs)
+	var t *template.Template
+	var err error
+	if r.opts.FileSystem != nil {
+		t, err = tmain.ParseFS(r.opts.FileSystem, tpls...)
+	} else {
+		t, err = tmain.ParseFiles(tpls...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t = r.bindInclude(t)
+
+	if cacheable {
+		r.templateSets.set(key, t)
+	}
+	return t, nil
+}
+
+// View build html from template directory and serve html content as response. See README.md for detail example.
+func (r *Render) View(w http.ResponseWriter, status int, name string, v interface{}, opts ...RenderOption) error {
+	r.setHTMLHeaders(w)
+
+	buf := r.getBuffer()
+	defer putBuffer(buf)
+	bs, err := r.renderView(buf, name, "", v, opts)
+	if err != nil {
+		return r.renderError(w, err)
+	}
+	if err = r.snapshot(name, bs); err != nil {
+		r.logf("renderer: %v", err)
+	}
+	r.detectHTMLCharset(w, bs)
+	return r.writeBody(w, status, bs)
+}
+
+// ViewWithLayout build html from template directory like View but execute the named layout of the directory
+// e.g: admin for admin.lout instead of the first one, so that a template can be rendered inside different layouts.
+// The snapshot is named by the template and the layout e.g: home@admin
+func (r *Render) ViewWithLayout(w http.ResponseWriter, status int, layout, name string, data interface{}) error {
+	r.setHTMLHeaders(w)
+
+	buf := r.getBuffer()
+	defer putBuffer(buf)
+	bs, err := r.renderView(buf, name, layout, data, nil)
+	if err != nil {
+		return r.renderError(w, err)
+	}
+	if err = r.snapshot(name+"@"+layout, bs); err != nil {
+		r.logf("renderer: %v", err)
+	}
+	r.detectHTMLCharset(w, bs)
+	return r.writeBody(w, status, bs)
+}
+
+// renderView execute the template of TemplateDir by name within the layout, an empty layout means the first one
+func (r *Render) renderView(buf *bytes.Buffer, name, layout string, v interface{}, opts []RenderOption) ([]byte, error) {
+	name += r.opts.TemplateExtension
+	master, exec, err := r.viewSets(name)
+	if err != nil {
+		return nil, err
+	}
+	if master == nil {
+		return nil, fmt.Errorf("renderer: template %s does not exist", name)
+	}
+
+	cfg := newRenderConfig(opts)
+	tmpl, err := r.executable(cfg, master, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	if layout == "" {
+		return r.renderHTML(buf, cfg.cacheKeyOf("view:"+name, v), v, tmpl.Execute)
+	}
+	layout += r.opts.LayoutExtension
+	if tmpl.Lookup(layout) == nil {
+		return nil, fmt.Errorf("renderer: layout %s does not exist", layout)
+	}
+	return r.renderHTML(buf, cfg.cacheKeyOf("view:"+name+"@"+layout, v), v, func(w io.Writer, data interface{}) error {
+		return tmpl.ExecuteTemplate(w, layout, data)
+	})
+}
+
+// Encode return the data encoded in the format (FormatJSON, FormatXML, FormatYAML or FormatTOML) as the JSON, XML,
+// YAML and TOML methods write it without a response writer e.g: to log or hash the payload. The JSONPrefix is not added
+func (r *Render) Encode(format string, v interface{}) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return r.json(v)
+	case FormatXML:
+		return r.xml(v)
+	case FormatYAML:
+		return r.yaml(v)
+	case FormatTOML:
+		return r.toml(v)
+	}
+	return nil, fmt.Errorf("renderer: unsupported format %s", format)
+}
+
+// RenderToBytes render the template by name without a response writer e.g: for emails, the template of TemplateDir
+// is rendered like View and the other names are rendered like HTML
+func (r *Render) RenderToBytes(name string, v interface{}, opts ...RenderOption) ([]byte, error) {
+	// the bytes are returned to the caller so the buffer is not pooled
+	if r.hasView(name + r.opts.TemplateExtension) {
+		return r.renderView(r.newBuffer(), name, "", v, opts)
+	}
+	return r.renderGlob(r.newBuffer(), name, v, opts)
+}
+
+// RenderToString render the template by name like RenderToBytes and return the output as string
+func (r *Render) RenderToString(name string, v interface{}, opts ...RenderOption) (string, error) {
+	bs, err := r.RenderToBytes(name, v, opts...)
+	return string(bs), err
+}
+
+// setDigest set the Content-MD5 and Digest headers of the content when AddDigest is set
+func (r *Render) setDigest(w http.ResponseWriter, bs []byte) {
+	if !r.opts.AddDigest {
+		return
+	}
+	md5Sum := md5.Sum(bs)
+	shaSum := sha256.Sum256(bs)
+	w.Header().Set(ContentMD5, base64.StdEncoding.EncodeToString(md5Sum[:]))
+	w.Header().Set(Digest, "SHA-256="+base64.StdEncoding.EncodeToString(shaSum[:]))
+}
+
+// closeReader close the reader when AutoCloseReader is set and the reader is an io.Closer
+func (r *Render) closeReader(reader io.Reader) {
+	if c, ok := reader.(io.Closer); ok && r.opts.AutoCloseReader {
+		c.Close()
+	}
+}
+
+// dispositionOf build the content disposition value for the filename
+func dispositionOf(filename string, inline bool) string {
+	if inline {
+		return fmt.Sprintf("%s; filename=%s", contentDispositionInline, filename)
+	}
+	return fmt.Sprintf("%s; filename=%s", contentDispositionAttachment, filename)
+}
+
+// detectContentType return the content type of the filename extension e.g: image/png, the sniffed content type
+// when the extension is unknown
+func detectContentType(filename, sniffed string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(filename)); contentType != "" {
+		return contentType
+	}
+	return sniffed
+}
+
+// Binary serve file as application/octet-stream response; you may add ContentDisposition by your own.
+func (r *Render) Binary(w http.ResponseWriter, status int, reader io.Reader, filename string, inline bool) error {
+	return r.binary(w, status, reader, dispositionOf(filename, inline), filename)
+}
+
+// BinaryWithDisposition serve file as application/octet-stream response with the raw content disposition value
+func (r *Render) BinaryWithDisposition(w http.ResponseWriter, status int, reader io.Reader, disposition string) error {
+	return r.binary(w, status, reader, disposition, "")
+}
+
+// binary serve file as binary response, the content type is detected from the filename or the content when DetectContentType is set
+func (r *Render) binary(w http.ResponseWriter, status int, reader io.Reader, disposition, filename string) error {
+	defer r.closeReader(reader)
+	bs, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	contentType := r.opts.ContentBinary
+	if r.opts.DetectContentType {
+		contentType = detectContentType(filename, http.DetectContentType(bs))
+	}
+	w.Header().Set(ContentDisposition, disposition)
+	w.Header().Set(ContentType, contentType)
+	r.setDigest(w, bs)
+	r.writeHeader(w, status)
+	return r.writeWrapped(w, bs)
+}
+
+// File serve file as response from io.Reader
+func (r *Render) File(w http.ResponseWriter, status int, reader io.Reader, filename string, inline bool) error {
+	return r.fileReader(w, status, reader, dispositionOf(filename, inline), filename)
+}
+
+// FileWithDisposition serve file as response from io.Reader with the raw content disposition value e.g: form-data; name="file",
+// the Range header of the bound request (WithRequest) is served as 206 Partial Content when the reader is an io.ReadSeeker
+func (r *Render) FileWithDisposition(w http.ResponseWriter, status int, reader io.Reader, disposition string) error {
+	return r.fileReader(w, status, reader, disposition, "")
+}
+
+// fileReader serve file as response from io.Reader, the content type is sniffed from the content or detected from
+// the filename when DetectContentType is set
+func (r *Render) fileReader(w http.ResponseWriter, status int, reader io.Reader, disposition, filename string) error {
+	defer r.closeReader(reader)
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		contentType, err := sniffContentType(rs)
+		if err != nil {
+			return err
+		}
+		if r.opts.DetectContentType {
+			contentType = detectContentType(filename, contentType)
+		}
+		w.Header().Set(ContentDisposition, disposition)
+		w.Header().Set(ContentType, contentType)
+		if ok, err := r.writeRange(w, status, rs); ok || err != nil {
+			return err
+		}
+	}
+
+	bs, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	// set headers
+	contentType := http.DetectContentType(bs)
+	if r.opts.DetectContentType {
+		contentType = detectContentType(filename, contentType)
+	}
+	w.Header().Set(ContentDisposition, disposition)
+	w.Header().Set(ContentType, contentType)
+	r.setDigest(w, bs)
+	r.writeHeader(w, status)
+
+	return r.writeWrapped(w, bs)
+}
+
+// ServeImmutable serve fingerprinted asset from io.Reader as inline response which may be cached forever by clients,
+// the content type is detected from the extension of name, then from the content
+func (r *Render) ServeImmutable(w http.ResponseWriter, status int, reader io.Reader, name string) error {
+	w.Header().Set(CacheControl, cacheControlImmutable)
+	c := *r
+	c.opts.DetectContentType = true
+	return c.File(w, status, reader, name, true)
+}
+
+// file serve file as response
+func (r *Render) file(w http.ResponseWriter, status int, fpath, name, contentDisposition string) error {
+	var bs []byte
+	var err error
+	info, err := os.Stat(fpath)
+	if err != nil {
+		return err
+	}
+	bs, err = ioutil.ReadFile(fpath)
+	if err != nil {
+		return err
+	}
 	// filename, ext, mimes
 	var fn, mime, ext string
 	fn, err = filepath.Abs(fpath)
@@ -512,17 +2127,22 @@ func (r *Render) file(w http.ResponseWriter, status int, fpath, name, contentDis
 	// set headers
 	w.Header().Set(ContentType, mime)
 	w.Header().Set(ContentDisposition, fmt.Sprintf("%s; filename=%s", contentDisposition, fn))
-	w.WriteHeader(status)
-
-	if _, err = buf.WriteTo(w); err != nil {
+	w.Header().Set(LastModified, info.ModTime().UTC().Format(http.TimeFormat))
+	if ok, err := r.writeRange(w, status, bytes.NewReader(bs)); ok || err != nil {
 		return err
 	}
+	if len(r.opts.WriterWrappers) == 0 {
+		// the wrappers may change the size of the body
+		w.Header().Set(ContentLength, strconv.Itoa(len(bs)))
+	}
+	r.setDigest(w, bs)
+	r.writeHeader(w, status)
 
-	_, err = w.Write(buf.Bytes())
-	return err
+	return r.writeWrapped(w, bs)
 }
 
-// FileView serve file as response with content-disposition value inline
+// FileView serve file as response with content-disposition value inline, the Range header of the bound request
+// (WithRequest) is served as 206 Partial Content
 func (r *Render) FileView(w http.ResponseWriter, status int, fpath, name string) error {
 	return r.file(w, status, fpath, name, contentDispositionInline)
 }
@@ -532,55 +2152,207 @@ func (r *Render) FileDownload(w http.ResponseWriter, status int, fpath, name str
 	return r.file(w, status, fpath, name, contentDispositionAttachment)
 }
 
-// parseTemplates parse all the template in the directory
-func (r *Render) parseTemplates() {
-	layouts, err := filepath.Glob(filepath.Join(r.opts.TemplateDir, "*"+r.opts.LayoutExtension))
+// parseError describes a failure to parse the templates again in Debug mode, it is already logged
+type parseError struct {
+	err error
+}
+
+func (e parseError) Error() string { return e.err.Error() }
+
+func (e parseError) Unwrap() error { return e.err }
+
+// globSets return the template sets of ParseGlobPattern, in Debug mode the templates are parsed on every call so
+// that the changes are picked without a restart and the last parsed sets are kept when the parsing fails
+func (r *Render) globSets() (master, exec *template.Template, err error) {
+	if !r.opts.Debug {
+		return r.globTemplates, r.globExec, nil
+	}
+	r.parseMu.Lock()
+	defer r.parseMu.Unlock()
+	if err = r.parseGlob(); err != nil {
+		r.logf("renderer: %v", err)
+		return nil, nil, parseError{err}
+	}
+	return r.globTemplates, r.globExec, nil
+}
+
+// viewSets return the template sets of the TemplateDir template by file name, nil when it does not exist. In
+// Debug mode the templates are parsed on every call like globSets
+func (r *Render) viewSets(name string) (master, exec *template.Template, err error) {
+	if !r.opts.Debug {
+		return r.templates[name], r.viewExec[name], nil
+	}
+	r.parseMu.Lock()
+	defer r.parseMu.Unlock()
+	if err = r.parseTemplates(); err != nil {
+		r.logf("renderer: %v", err)
+		return nil, nil, parseError{err}
+	}
+	return r.templates[name], r.viewExec[name], nil
+}
+
+// hasView report whether the TemplateDir template exists by file name, the templates may be parsed concurrently
+// in Debug mode
+func (r *Render) hasView(name string) bool {
+	if r.opts.Debug {
+		r.parseMu.Lock()
+		defer r.parseMu.Unlock()
+	}
+	_, ok := r.templates[name]
+	return ok
+}
+
+// parseTemplates parse all the template in the directory, the parsed templates replace the previous ones only
+// when every template is parsed
+func (r *Render) parseTemplates() error {
+	glob := filepath.Glob
+	join := filepath.Join
+	if r.opts.FileSystem != nil {
+		glob = func(pattern string) ([]string, error) { return fs.Glob(r.opts.FileSystem, pattern) }
+		join = path.Join
+	}
+
+	layouts, err := glob(join(r.opts.TemplateDir, "*"+r.opts.LayoutExtension))
 	if err != nil {
-		panic(fmt.Errorf("renderer: %s", err.Error()))
+		return fmt.Errorf("renderer: %s", err.Error())
 	}
 
-	tpls, err := filepath.Glob(filepath.Join(r.opts.TemplateDir, "*"+r.opts.TemplateExtension))
+	tpls, err := glob(join(r.opts.TemplateDir, "*"+r.opts.TemplateExtension))
 	if err != nil {
-		panic(fmt.Errorf("renderer: %s", err.Error()))
+		return fmt.Errorf("renderer: %s", err.Error())
 	}
 
+	templates := make(map[string]*template.Template, len(tpls))
+	viewExec := make(map[string]*template.Template, len(tpls))
 	for _, tpl := range tpls {
-		files := append(layouts, tpl)
-		fn := filepath.Base(tpl)
-		// TODO: add FuncMap and Delims
-		// tmpl := template.New(fn)
-		// tmpl.Delims(r.opts.LeftDelim, r.opts.RightDelim)
-		// for _, fm := range r.opts.FuncMap {
-		// 	tmpl.Funcs(fm)
-		// }
-		r.templates[fn] = template.Must(template.ParseFiles(files...))
+		files := append(layouts[:len(layouts):len(layouts)], tpl)
+		fn := path.Base(filepath.ToSlash(tpl))
+		// the template set is named by the first file like template.ParseFiles does
+		tmpl := template.New(path.Base(filepath.ToSlash(files[0])))
+		tmpl.Delims(r.opts.LeftDelim, r.opts.RightDelim)
+		tmpl.Funcs(r.templateFuncs())
+		for _, fm := range r.opts.FuncMap {
+			tmpl.Funcs(fm)
+		}
+		if r.opts.FileSystem != nil {
+			_, err = tmpl.ParseFS(r.opts.FileSystem, files...)
+		} else {
+			_, err = tmpl.ParseFiles(files...)
+		}
+		if err != nil {
+			return err
+		}
+		// the master is never executed so that it can be cloned with per call funcs
+		exec, err := tmpl.Clone()
+		if err != nil {
+			return err
+		}
+		templates[fn] = tmpl
+		viewExec[fn] = r.bindInclude(exec)
+	}
+	// the maps are replaced rather than updated as the previous ones may still be read
+	r.templates, r.viewExec = templates, viewExec
+	return nil
+}
+
+// parseRecursiveGlob parse the templates matching a recursive pattern e.g: views/**/*.html, the files of every
+// nested directory matching the file pattern are parsed and named by their slash separated path relative to the root.
+// The OS filesystem is used when fsys is nil
+func parseRecursiveGlob(tmpl *template.Template, fsys fs.FS, pattern string) error {
+	parts := strings.SplitN(filepath.ToSlash(pattern), "**", 2)
+	root := strings.TrimSuffix(parts[0], "/")
+	if root == "" {
+		root = "."
+	}
+	filePattern := strings.TrimPrefix(parts[1], "/")
+	if filePattern == "" || strings.Contains(filePattern, "/") {
+		return fmt.Errorf("renderer: invalid recursive glob pattern %s", pattern)
+	}
+	if fsys == nil {
+		fsys = os.DirFS(filepath.FromSlash(root))
+		root = "."
 	}
+	return fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ok, merr := path.Match(filePattern, d.Name()); merr != nil || !ok {
+			return merr
+		}
+		bs, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		name := p
+		if root != "." {
+			name = strings.TrimPrefix(p, root+"/")
+		}
+		_, err = tmpl.New(name).Parse(string(bs))
+		return err
+	})
 }
 
-// parseGlob parse templates using ParseGlob
-func (r *Render) parseGlob() {
+// parseGlob parse templates using ParseGlob, the parsed templates replace the previous ones only when every
+// pattern is parsed
+func (r *Render) parseGlob() error {
 	tmpl := template.New("")
 	tmpl.Delims(r.opts.LeftDelim, r.opts.RightDelim)
+	tmpl.Funcs(r.templateFuncs())
 	for _, fm := range r.opts.FuncMap {
 		tmpl.Funcs(fm)
 	}
-	if !strings.Contains(r.opts.ParseGlobPattern, "*.") {
-		log.Fatal("renderer: invalid glob pattern!")
+	for _, pattern := range r.globPatterns() {
+		if err := r.parseGlobPattern(tmpl, pattern); err != nil {
+			return err
+		}
+	}
+	// the master is never executed so that it can be cloned with per call funcs
+	exec, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	r.globTemplates = tmpl
+	r.globExec = r.bindInclude(exec)
+	return nil
+}
+
+// logf report the message to the Logger, or to the standard logger when the Logger is not set
+func (r *Render) logf(format string, args ...interface{}) {
+	if r.opts.Logger != nil {
+		r.opts.Logger.Printf(format, args...)
+		return
 	}
-	pf := strings.Split(r.opts.ParseGlobPattern, "*")
+	log.Printf(format, args...)
+}
+
+// globPatterns return the ParseGlobPattern followed by the ParseGlobPatterns
+func (r *Render) globPatterns() []string {
+	var patterns []string
+	if r.opts.ParseGlobPattern != "" {
+		patterns = append(patterns, r.opts.ParseGlobPattern)
+	}
+	return append(patterns, r.opts.ParseGlobPatterns...)
+}
+
+// parseGlobPattern parse the templates matching the pattern into the template set
+func (r *Render) parseGlobPattern(tmpl *template.Template, pattern string) error {
+	if strings.Contains(pattern, "**") {
+		return parseRecursiveGlob(tmpl, r.opts.FileSystem, pattern)
+	}
+	if r.opts.FileSystem != nil {
+		_, err := tmpl.ParseFS(r.opts.FileSystem, pattern)
+		return err
+	}
+	if !strings.Contains(pattern, "*.") {
+		return errors.New("renderer: invalid glob pattern!")
+	}
+	pf := strings.Split(pattern, "*")
 	fPath := pf[0]
 	fExt := pf[1]
-	err := filepath.Walk(fPath, func(path string, info os.FileInfo, err error) error {
+	return filepath.Walk(fPath, func(path string, info os.FileInfo, err error) error {
 		if strings.Contains(path, fExt) {
 			_, err = tmpl.ParseFiles(path)
-			if err != nil {
-				log.Println(err)
-			}
 		}
 		return err
 	})
-	if err != nil {
-		log.Fatal(err)
-	}
-	r.globTemplates = tmpl
 }
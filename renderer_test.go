@@ -1,15 +1,28 @@
 package renderer
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 type user struct {
@@ -70,6 +83,12 @@ func Test_DisableCharset_new(t *testing.T) {
 	}
 }
 
+func Test_UpperCaseCharset(t *testing.T) {
+	r := New(Options{UpperCaseCharset: true})
+	checkContentType(t, r.opts.ContentJSON, ContentJSON+"; charset=UTF-8")
+	checkContentType(t, r.opts.ContentHTML, ContentHTML+"; charset=UTF-8")
+}
+
 func Test_JSONIndent(t *testing.T) {
 	r := New()
 	r.JSONIndent(true)
@@ -155,6 +174,18 @@ func Test_Render(t *testing.T) {
 	checkBody(t, res.Body.String(), expected)
 }
 
+func Test_Status(t *testing.T) {
+	r := New()
+	res := httptest.NewRecorder()
+	err := r.Status(res, http.StatusNotFound)
+	checkNil(t, err)
+	if res.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, res.Code)
+	}
+	checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), "Not Found")
+}
+
 func Test_String(t *testing.T) {
 	r := New()
 	var err error
@@ -176,6 +207,102 @@ func Test_String(t *testing.T) {
 	checkBody(t, res.Body.String(), expected)
 }
 
+// upperWriter uppercase the bytes written to the writer
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u upperWriter) Write(p []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(p))
+}
+
+func Test_WriterWrappers(t *testing.T) {
+	var written int
+	r := New(Options{
+		WriterWrappers: []func(io.Writer) io.Writer{
+			func(w io.Writer) io.Writer {
+				return writerFunc(func(p []byte) (int, error) {
+					written += len(p)
+					return w.Write(p)
+				})
+			},
+			func(w io.Writer) io.Writer { return upperWriter{w} },
+		},
+	})
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.String(w, http.StatusOK, "Hello John")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), "HELLO JOHN")
+	if written != len("Hello John") {
+		t.Errorf("expected %d bytes counted, got: %d", len("Hello John"), written)
+	}
+
+	res = httptest.NewRecorder()
+	checkNil(t, r.JSON(res, http.StatusOK, M{"name": "John"}))
+	checkBody(t, res.Body.String(), `{"NAME":"JOHN"}`)
+}
+
+// writerFunc describes a function used as io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func Test_StringWithType(t *testing.T) {
+	r := New()
+	var err error
+
+	data := "body{color:red}"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.StringWithType(w, http.StatusOK, "text/css", data)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/style.css", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), "text/css; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), data)
+}
+
+func Test_Properties(t *testing.T) {
+	r := New()
+	var err error
+
+	data := map[string]string{
+		"DB_HOST": "localhost",
+		"APP_ENV": "production",
+		"MOTD":    "hello\nworld",
+	}
+	expected := "APP_ENV=production\nDB_HOST=localhost\nMOTD=hello\\nworld\n"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.Properties(w, http.StatusOK, data)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/config", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
 func Test_json(t *testing.T) {
 	r := New()
 	var err error
@@ -213,167 +340,1046 @@ func Test_json_indent_unescapehtml(t *testing.T) {
 	checkBody(t, string(bs), expected)
 }
 
-func Test_JSON_prefix(t *testing.T) {
-	r := New(
-		Options{
-			JSONPrefix: "\n",
-		},
-	)
+func Test_json_max_indent_depth(t *testing.T) {
+	r := New(Options{
+		JSONIndent:         true,
+		JSONMaxIndentDepth: 2,
+	})
 	var err error
+	var bs []byte
 
-	usr := user{"John Doe", 30}
-	expected := "\n{\"Name\":\"John Doe\",\"Age\":30}"
+	v := M{
+		"name": "John Doe",
+		"address": M{
+			"city": "Dhaka",
+			"geo":  M{"lat": 23.8, "lng": 90.4},
+			"tags": []string{},
+		},
+	}
+	expected := "{\n \"address\": {\n  \"city\": \"Dhaka\",\n  \"geo\": {\"lat\":23.8,\"lng\":90.4},\n  \"tags\": []\n },\n \"name\": \"John Doe\"\n}"
+	bs, err = r.json(v)
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.JSON(w, http.StatusOK, usr)
+	checkNil(t, err)
+	checkBody(t, string(bs), expected)
+
+	// without a limit the output matches json.MarshalIndent
+	r = New(Options{
+		JSONIndent:         true,
+		JSONMaxIndentDepth: 100,
 	})
+	bs, err = r.json(v)
+	want, _ := json.MarshalIndent(v, "", " ")
 
-	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/json", nil)
-	h.ServeHTTP(res, req)
+	checkNil(t, err)
+	checkBody(t, string(bs), string(want))
+}
+
+func Test_json_force_array(t *testing.T) {
+	r := New(Options{
+		ForceArray: true,
+	})
+	var err error
+
+	_, err = r.json(map[string]string{"name": "John Doe"})
+	checkNotNil(t, err)
 
+	_, err = r.json(&[]user{{"John Doe", 30}})
 	checkNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
 }
 
-func Test_JSONP(t *testing.T) {
-	r := New(
-		Options{
-			JSONPrefix: "\n",
-		},
-	)
+func Test_json_force_object(t *testing.T) {
+	r := New(Options{
+		ForceObject: true,
+	})
 	var err error
 
-	usr := user{"John Doe", 30}
-	expected := "jsonp({\"Name\":\"John Doe\",\"Age\":30});"
+	_, err = r.json([]user{{"John Doe", 30}})
+	checkNotNil(t, err)
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.JSONP(w, http.StatusOK, "jsonp", usr)
+	_, err = r.json(&user{"John Doe", 30})
+	checkNil(t, err)
+}
+
+func Test_json_type_tag(t *testing.T) {
+	r := New(Options{
+		TypeTag: true,
 	})
 
-	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/jsonp", nil)
-	h.ServeHTTP(res, req)
+	bs, err := r.json(&user{"John Doe", 30})
+	checkNil(t, err)
+	var got map[string]interface{}
+	checkNil(t, json.Unmarshal(bs, &got))
+	if got["type"] != "user" {
+		t.Errorf("expected type: user, got: %v", got["type"])
+	}
+	checkBody(t, string(bs), `{"type":"user","Name":"John Doe","Age":30}`)
 
+	bs, err = r.json([]user{{"John Doe", 30}})
 	checkNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentJSONP+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
+	checkBody(t, string(bs), `[{"Name":"John Doe","Age":30}]`)
 }
 
-func Test_JSONP_without_callback(t *testing.T) {
-	r := New(
-		Options{
-			JSONPrefix: "\n",
-		},
-	)
-	var err error
+// unorderedMarshaler encode its map in the iteration order of the map
+type unorderedMarshaler map[string]int
 
-	usr := user{"John Doe", 30}
+func (u unorderedMarshaler) MarshalJSON() ([]byte, error) {
+	parts := make([]string, 0, len(u))
+	for k, v := range u {
+		parts = append(parts, fmt.Sprintf("%q:%d", k, v))
+	}
+	return []byte("{" + strings.Join(parts, ",") + "}"), nil
+}
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.JSONP(w, http.StatusOK, "", usr)
+func Test_json_deterministic(t *testing.T) {
+	r := New(Options{
+		Deterministic: true,
+		TimeFormat:    time.RFC3339,
 	})
+	v := struct {
+		Name    string             `json:"name"`
+		Scores  unorderedMarshaler `json:"scores"`
+		Created time.Time          `json:"created"`
+		Note    string             `json:"note,omitempty"`
+	}{
+		Name:    "John Doe",
+		Scores:  unorderedMarshaler{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8},
+		Created: time.Date(2017, 10, 1, 14, 30, 0, 0, time.FixedZone("BDT", 6*60*60)),
+	}
 
-	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/jsonp", nil)
-	h.ServeHTTP(res, req)
+	want, err := r.json(v)
+	checkNil(t, err)
+	checkBody(t, string(want), `{"name":"John Doe","scores":{"a":1,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":8},"created":"2017-10-01T08:30:00Z"}`)
+	for i := 0; i < 20; i++ {
+		bs, err := r.json(v)
+		checkNil(t, err)
+		checkBody(t, string(bs), string(want))
+	}
+}
 
-	checkNotNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentJSONP+"; charset="+defaultCharSet)
+func Test_json_TimeFormat(t *testing.T) {
+	r := New(Options{TimeFormat: "2006-01-02"})
+	v := struct {
+		Name    string     `json:"name"`
+		Addr    netip.Addr `json:"addr"`
+		ID      int64      `json:"id,string"`
+		Created time.Time  `json:"created"`
+		Updated *time.Time `json:"updated,omitempty"`
+	}{
+		Name:    "John Doe",
+		Addr:    netip.MustParseAddr("10.0.0.1"),
+		ID:      42,
+		Created: time.Date(2017, 10, 1, 14, 30, 0, 0, time.UTC),
+	}
+
+	bs, err := r.json(v)
+	checkNil(t, err)
+	checkBody(t, string(bs), `{"name":"John Doe","addr":"10.0.0.1","id":"42","created":"2017-10-01"}`)
+
+	// the strings looking like a time are data, the embedded fields are promoted
+	type audit struct {
+		Created time.Time `json:"created"`
+		Name    string    `json:"name"`
+	}
+	w := struct {
+		ID string `json:"id"`
+		audit
+		Events map[string]time.Time `json:"events"`
+	}{
+		ID:     "2020-01-01T00:00:00Z",
+		audit:  audit{Created: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Name: "2020-01-01T00:00:00Z"},
+		Events: map[string]time.Time{"b": time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), "a": time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	bs, err = r.json(w)
+	checkNil(t, err)
+	checkBody(t, string(bs), `{"id":"2020-01-01T00:00:00Z","created":"2020-01-01","name":"2020-01-01T00:00:00Z","events":{"a":"2020-01-03","b":"2020-01-02"}}`)
 }
 
-func Test_XML(t *testing.T) {
-	r := New()
-	var err error
+// requiredValidator validate only the required keywords of a JSON Schema
+func requiredValidator(schema, document []byte) error {
+	var sc struct {
+		Required []string `json:"required"`
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(schema, &sc); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return err
+	}
+	for _, k := range sc.Required {
+		if _, ok := doc[k]; !ok {
+			return fmt.Errorf("missing required property %s", k)
+		}
+	}
+	return nil
+}
 
+func Test_json_schema_validation(t *testing.T) {
+	opts := Options{
+		JSONSchema:          []byte(`{"type":"object","required":["Name","Email"]}`),
+		JSONSchemaValidator: requiredValidator,
+	}
 	usr := user{"John Doe", 30}
-	expected := `<?xml version="1.0" encoding="ISO-8859-1" ?>\n<user><Name>John Doe</Name><Age>30</Age></user>`
+	var err error
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.XML(w, http.StatusOK, usr)
+	// validation is gated behind Debug
+	_, err = New(opts).json(usr)
+	checkNil(t, err)
+
+	opts.Debug = true
+	_, err = New(opts).json(usr)
+	checkNotNil(t, err)
+
+	_, err = New(opts).json(M{"Name": "John Doe", "Email": "john@example.com"})
+	checkNil(t, err)
+}
+
+func Test_json_ascii_only(t *testing.T) {
+	r := New(Options{
+		ASCIIOnlyJSON: true,
 	})
+	var err error
+	var bs []byte
 
-	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/xml", nil)
-	h.ServeHTTP(res, req)
+	bs, err = r.json(M{"name": "café 😀"})
+	expected := `{"name":"caf\u00e9 \ud83d\ude00"}`
 
 	checkNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentXML+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
+	checkBody(t, string(bs), expected)
 }
 
-func Test_XML_indent_prefix(t *testing.T) {
+func Test_JSON_prefix(t *testing.T) {
 	r := New(
 		Options{
-			XMLIndent: true,
-			XMLPrefix: " ",
+			JSONPrefix: "\n",
 		},
 	)
 	var err error
 
 	usr := user{"John Doe", 30}
-	expected := " <user>\n <Name>John Doe</Name>\n <Age>30</Age>\n</user>"
+	expected := "\n{\"Name\":\"John Doe\",\"Age\":30}"
 
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.XML(w, http.StatusOK, usr)
+		err = r.JSON(w, http.StatusOK, usr)
 	})
 
 	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/xml", nil)
+	req, _ := http.NewRequest("GET", "/json", nil)
 	h.ServeHTTP(res, req)
 
 	checkNil(t, err)
 	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentXML+"; charset="+defaultCharSet)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset="+defaultCharSet)
 	checkBody(t, res.Body.String(), expected)
 }
 
-func Test_YAML(t *testing.T) {
-	r := New()
-	var err error
+func Test_Error(t *testing.T) {
+	cases := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{}, `{"error":"invalid id"}`},
+		{Options{IncludeStatusCode: true}, `{"error":"invalid id","status":400}`},
+		{Options{IncludeStatusText: true}, `{"error":"invalid id","status_text":"Bad Request"}`},
+		{Options{IncludeStatusCode: true, IncludeStatusText: true}, `{"error":"invalid id","status":400,"status_text":"Bad Request"}`},
+	}
 
-	usr := user{"John Doe", 30}
-	expected := "name: John Doe\nage: 30\n"
+	for _, c := range cases {
+		r := New(c.opts)
+		var err error
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.YAML(w, http.StatusOK, usr)
-	})
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.Error(w, http.StatusBadRequest, "invalid id")
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/users/abc", nil)
+		h.ServeHTTP(res, req)
 
+		checkNil(t, err)
+		if res.Code != http.StatusBadRequest {
+			t.Error("http status code should be 400")
+		}
+		checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset="+defaultCharSet)
+		checkBody(t, res.Body.String(), c.expected)
+	}
+
+	// the body carries the DefaultStatus sent for 0
+	r := New(Options{IncludeStatusCode: true, IncludeStatusText: true, DefaultStatus: http.StatusBadRequest})
 	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/yaml", nil)
-	h.ServeHTTP(res, req)
+	checkNil(t, r.Error(res, 0, "invalid id"))
+	if res.Code != http.StatusBadRequest {
+		t.Error("http status code should be 400")
+	}
+	checkBody(t, res.Body.String(), `{"error":"invalid id","status":400,"status_text":"Bad Request"}`)
+}
 
+func Test_JSON_JSONMarshaler(t *testing.T) {
+	sentinel := []byte(`{"encoder":"custom"}`)
+	r := New()
+	r.JSONMarshaler(func(v interface{}) ([]byte, error) {
+		return sentinel, nil
+	})
+	res := httptest.NewRecorder()
+	err := r.JSON(res, http.StatusOK, map[string]string{"encoder": "default"})
 	checkNil(t, err)
 	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentYAML+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
+	checkContentType(t, res.Header().Get(ContentType), r.opts.ContentJSON)
+	checkBody(t, res.Body.String(), string(sentinel))
+
+	r.JSONMarshaler(nil)
+	res = httptest.NewRecorder()
+	err = r.JSON(res, http.StatusOK, map[string]string{"encoder": "default"})
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"encoder":"default"}`)
 }
 
-func Test_HTMLString(t *testing.T) {
-	r := New()
-	var err error
+func Test_JSONMarshaler_streams_and_fields(t *testing.T) {
+	calls := 0
+	r := New(Options{
+		UnEscapeHTML: true,
+		JSONIndent:   true,
+		TypeTag:      true,
+		JSONMarshaler: func(v interface{}) ([]byte, error) {
+			calls++
+			return json.Marshal(v)
+		},
+	})
+	v := M{"name": "<b>John</b>", "age": 30}
+	cases := map[string]struct {
+		render   func(w http.ResponseWriter) error
+		expected string
+	}{
+		"JSONFields": {
+			func(w http.ResponseWriter) error { return r.JSONFields(w, http.StatusOK, v, []string{"name"}) },
+			`{"name":"<b>John</b>"}`,
+		},
+		"JSONStream": {
+			func(w http.ResponseWriter) error {
+				items := make(chan interface{}, 1)
+				items <- v
+				close(items)
+				return r.JSONStream(w, http.StatusOK, items)
+			},
+			`{"age":30,"name":"<b>John</b>"}` + "\n",
+		},
+		"ManifestStream": {
+			func(w http.ResponseWriter) error {
+				return r.ManifestStream(w, http.StatusOK, []interface{}{v}, ManifestNDJSON)
+			},
+			`{"age":30,"name":"<b>John</b>"}` + "\n",
+		},
+		"LogStream": {
+			func(w http.ResponseWriter) error {
+				return r.LogStream(w, http.StatusOK, []LogEntry{{Level: "info", Message: "<ok>"}})
+			},
+			`{"type":"LogEntry","timestamp":"0001-01-01T00:00:00Z","level":"info","message":"<ok>"}` + "\n",
+		},
+	}
 
-	data := "<h1>Hello John</h1>"
-	expected := `<h1>Hello John</h1>`
+	for name, c := range cases {
+		calls = 0
+		res := httptest.NewRecorder()
+		checkNil(t, c.render(res))
+		checkBody(t, res.Body.String(), c.expected)
+		if calls == 0 {
+			t.Errorf("%s should encode with the JSONMarshaler", name)
+		}
+	}
+}
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.HTMLString(w, http.StatusOK, data)
-	})
+func Test_JSONWithCharset(t *testing.T) {
+	r := New()
+	usr := user{"John Doe", 30}
 
 	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/htmlstring", nil)
-	h.ServeHTTP(res, req)
-
+	err := r.JSONWithCharset(res, http.StatusOK, usr, "ISO-8859-1")
 	checkNil(t, err)
 	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
-}
+	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset=ISO-8859-1")
+
+	res = httptest.NewRecorder()
+	err = r.XMLWithCharset(res, http.StatusOK, usr, "ISO-8859-1")
+	checkNil(t, err)
+	checkContentType(t, res.Header().Get(ContentType), ContentXML+"; charset=ISO-8859-1")
+
+	// the following calls use the default charset
+	res = httptest.NewRecorder()
+	err = r.JSON(res, http.StatusOK, usr)
+	checkNil(t, err)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset="+defaultCharSet)
+}
+
+func Test_JSONPretty(t *testing.T) {
+	r := New()
+	usr := user{"John Doe", 30}
+
+	res := httptest.NewRecorder()
+	err := r.JSONPretty(res, http.StatusOK, usr)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), r.opts.ContentJSON)
+	checkBody(t, res.Body.String(), "{\n \"Name\": \"John Doe\",\n \"Age\": 30\n}")
+
+	// JSON keeps honoring the option
+	res = httptest.NewRecorder()
+	err = r.JSON(res, http.StatusOK, usr)
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"Name":"John Doe","Age":30}`)
+}
+
+func Test_JSONBlob(t *testing.T) {
+	r := New()
+	raw := `{"name":"John Doe",  "age":30}`
+
+	res := httptest.NewRecorder()
+	err := r.JSONBlob(res, http.StatusOK, []byte(raw))
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset=utf-8")
+	checkBody(t, res.Body.String(), raw)
+
+	res = httptest.NewRecorder()
+	err = r.JSONReader(res, http.StatusOK, strings.NewReader(raw))
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset=utf-8")
+	checkBody(t, res.Body.String(), raw)
+
+	// HEAD is answered with the headers only
+	res = httptest.NewRecorder()
+	req, _ := http.NewRequest("HEAD", "/user", nil)
+	err = r.WithRequest(req).JSONReader(res, http.StatusOK, strings.NewReader(raw))
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), "")
+	checkBody(t, res.Header().Get(ContentLength), strconv.Itoa(len(raw)))
+}
+
+func Test_JSONFields(t *testing.T) {
+	r := New()
+	usr := user{"John Doe", 30}
+
+	res := httptest.NewRecorder()
+	err := r.JSONFields(res, http.StatusOK, usr, []string{"Name", "Email"})
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), r.opts.ContentJSON)
+	checkBody(t, res.Body.String(), `{"Name":"John Doe"}`)
+
+	res = httptest.NewRecorder()
+	err = r.JSONFields(res, http.StatusOK, []user{usr}, []string{"Name"})
+	checkNotNil(t, err)
+	checkBody(t, res.Body.String(), "")
+}
+
+func Test_DefaultStatus(t *testing.T) {
+	usr := user{"John Doe", 30}
+
+	res := httptest.NewRecorder()
+	err := New().JSON(res, 0, usr)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `{"Name":"John Doe","Age":30}`)
+
+	r := New(Options{DefaultStatus: http.StatusCreated})
+	res = httptest.NewRecorder()
+	err = r.JSON(res, 0, usr)
+	checkNil(t, err)
+	if res.Code != http.StatusCreated {
+		t.Errorf("expected status %d, got %d", http.StatusCreated, res.Code)
+	}
+
+	// an explicit status is kept
+	res = httptest.NewRecorder()
+	err = r.JSON(res, http.StatusAccepted, usr)
+	checkNil(t, err)
+	if res.Code != http.StatusAccepted {
+		t.Errorf("expected status %d, got %d", http.StatusAccepted, res.Code)
+	}
+}
+
+func Test_JSON_envelope(t *testing.T) {
+	r := New(Options{JSONEnvelope: true})
+	usr := user{"John Doe", 30}
+
+	res := httptest.NewRecorder()
+	err := r.JSON(res, http.StatusOK, usr)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `{"data":{"Name":"John Doe","Age":30},"error":null}`)
+
+	res = httptest.NewRecorder()
+	err = r.JSONWrapped(res, http.StatusOK, []user{usr}, M{"total": 1})
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"data":[{"Name":"John Doe","Age":30}],"error":null,"meta":{"total":1}}`)
+
+	// the error fills the envelope error and leaves the data null
+	res = httptest.NewRecorder()
+	err = r.Error(res, http.StatusBadRequest, "bad id")
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"data":null,"error":"bad id"}`)
+
+	res = httptest.NewRecorder()
+	err = New(Options{JSONEnvelope: true, IncludeStatusCode: true}).Error(res, http.StatusBadRequest, "bad id")
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"data":null,"error":"bad id","meta":{"status":400}}`)
+
+	// the health report is not wrapped
+	res = httptest.NewRecorder()
+	err = r.Health(res, http.StatusOK, map[string]error{"db": nil})
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"status":"ok","checks":{"db":"ok"}}`)
+
+	// the envelope is not applied when disabled
+	res = httptest.NewRecorder()
+	err = New().JSON(res, http.StatusOK, usr)
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `{"Name":"John Doe","Age":30}`)
+}
+
+func Test_JSON_StatusContentType(t *testing.T) {
+	r := New(Options{
+		StatusContentType: map[int]string{
+			4:                          "application/problem+json",
+			5:                          "application/problem+json",
+			http.StatusTooManyRequests: "application/vnd.limit+json",
+		},
+	})
+	cases := map[int]string{
+		http.StatusOK:                  ContentJSON,
+		http.StatusBadRequest:          "application/problem+json",
+		http.StatusInternalServerError: "application/problem+json",
+		http.StatusTooManyRequests:     "application/vnd.limit+json",
+	}
+
+	for status, contentType := range cases {
+		res := httptest.NewRecorder()
+		err := r.Error(res, status, http.StatusText(status))
+
+		checkNil(t, err)
+		if res.Code != status {
+			t.Errorf("expected status: %d, got: %d", status, res.Code)
+		}
+		checkContentType(t, res.Header().Get(ContentType), contentType+"; charset="+defaultCharSet)
+	}
+
+	// the class of the DefaultStatus sent for 0 is mapped
+	r.opts.DefaultStatus = http.StatusServiceUnavailable
+	res := httptest.NewRecorder()
+	checkNil(t, r.JSON(res, 0, M{"ok": false}))
+	checkContentType(t, res.Header().Get(ContentType), "application/problem+json; charset="+defaultCharSet)
+}
+
+func Test_JSON_APIVersion(t *testing.T) {
+	r := New(Options{
+		APIVersion:      "2.1",
+		APIVersionField: true,
+		TypeTag:         true,
+	})
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSON(w, http.StatusOK, user{"John Doe", 30})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/user", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Header().Get(APIVersionHeader), "2.1")
+	checkBody(t, res.Body.String(), `{"version":"2.1","type":"user","Name":"John Doe","Age":30}`)
+
+	// the header is sent by the other methods as well
+	res = httptest.NewRecorder()
+	r.String(res, http.StatusOK, "Hello John")
+	checkBody(t, res.Header().Get(APIVersionHeader), "2.1")
+}
+
+func Test_encode_error_writes_nothing(t *testing.T) {
+	r := New()
+	v := M{"callback": func() {}}
+	methods := map[string]func(w http.ResponseWriter) error{
+		"JSON": func(w http.ResponseWriter) error { return r.JSON(w, http.StatusOK, v) },
+		"XML":  func(w http.ResponseWriter) error { return r.XML(w, http.StatusOK, func() {}) },
+		"YAML": func(w http.ResponseWriter) error { return r.YAML(w, http.StatusOK, v) },
+		"TOML": func(w http.ResponseWriter) error { return r.TOML(w, http.StatusOK, v) },
+	}
+
+	for name, render := range methods {
+		res := httptest.NewRecorder()
+		err := render(res)
+		if err == nil {
+			t.Errorf("%s: expected encode error", name)
+		}
+		if res.Code != http.StatusOK || res.Body.Len() != 0 || len(res.Header()) != 0 || res.Flushed {
+			t.Errorf("%s: nothing should be written on encode error", name)
+		}
+
+		// the caller is still able to respond
+		r.String(res, http.StatusInternalServerError, "encode failed")
+		if res.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected status: %d, got: %d", name, http.StatusInternalServerError, res.Code)
+		}
+		checkBody(t, res.Body.String(), "encode failed")
+	}
+}
+
+func Test_Health(t *testing.T) {
+	r := New()
+
+	res := httptest.NewRecorder()
+	err := r.Health(res, http.StatusOK, map[string]error{"db": nil, "cache": errors.New("connection refused")})
+	checkNil(t, err)
+	if res.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status: %d, got: %d", http.StatusServiceUnavailable, res.Code)
+	}
+	checkBody(t, res.Body.String(), `{"status":"fail","checks":{"cache":"connection refused","db":"ok"}}`)
+
+	res = httptest.NewRecorder()
+	err = r.Health(res, http.StatusOK, map[string]error{"db": nil})
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `{"status":"ok","checks":{"db":"ok"}}`)
+}
+
+func Test_JSONP(t *testing.T) {
+	r := New(
+		Options{
+			JSONPrefix: "\n",
+		},
+	)
+	var err error
+
+	usr := user{"John Doe", 30}
+	expected := "jsonp({\"Name\":\"John Doe\",\"Age\":30});"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONP(w, http.StatusOK, "jsonp", usr)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/jsonp", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSONP+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_JSONP_without_callback(t *testing.T) {
+	r := New(
+		Options{
+			JSONPrefix: "\n",
+		},
+	)
+	var err error
+
+	usr := user{"John Doe", 30}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONP(w, http.StatusOK, "", usr)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/jsonp", nil)
+	h.ServeHTTP(res, req)
+
+	checkNotNil(t, err)
+	if res.Flushed || res.Header().Get(ContentType) != "" || res.Body.Len() != 0 {
+		t.Error("nothing should be written for an empty callback")
+	}
+}
+
+func Test_JSONP_callback_validation(t *testing.T) {
+	r := New()
+	cases := map[string]bool{
+		"callback":      true,
+		"$jsonp_1":      true,
+		"ns.cb":         true,
+		"app.api.$done": true,
+		"alert(1)//":    false,
+		"cb;alert(1)":   false,
+		"ns..cb":        false,
+		"1cb":           false,
+		"cb</script>":   false,
+	}
+	for callback, valid := range cases {
+		res := httptest.NewRecorder()
+		err := r.JSONP(res, http.StatusOK, callback, M{"ok": true})
+		if valid {
+			checkNil(t, err)
+			checkBody(t, res.Body.String(), callback+`({"ok":true});`)
+			continue
+		}
+		checkNotNil(t, err)
+		checkBody(t, res.Body.String(), "")
+		checkContentType(t, res.Header().Get(ContentType), "")
+	}
+
+	// the encoding error is returned before anything is written
+	res := httptest.NewRecorder()
+	checkNotNil(t, r.JSONP(res, http.StatusCreated, "callback", M{"fn": func() {}}))
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), "")
+}
+
+func Test_XML(t *testing.T) {
+	r := New()
+	var err error
+
+	usr := user{"John Doe", 30}
+	expected := `<?xml version="1.0" encoding="ISO-8859-1" ?>\n<user><Name>John Doe</Name><Age>30</Age></user>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.XML(w, http.StatusOK, usr)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/xml", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentXML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_XML_indent_prefix(t *testing.T) {
+	r := New(
+		Options{
+			XMLIndent: true,
+			XMLPrefix: " ",
+		},
+	)
+	var err error
+
+	usr := user{"John Doe", 30}
+	expected := " <user>\n <Name>John Doe</Name>\n <Age>30</Age>\n</user>"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.XML(w, http.StatusOK, usr)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/xml", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentXML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_indent_value(t *testing.T) {
+	r := New(
+		Options{
+			JSONIndent:      true,
+			JSONIndentValue: "  ",
+			XMLIndent:       true,
+			XMLIndentValue:  "  ",
+			XMLPrefix:       " ",
+		},
+	)
+	usr := user{"John Doe", 30}
+
+	res := httptest.NewRecorder()
+	err := r.JSON(res, http.StatusOK, usr)
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), "{\n  \"Name\": \"John Doe\",\n  \"Age\": 30\n}")
+
+	res = httptest.NewRecorder()
+	err = r.XML(res, http.StatusOK, usr)
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), " <user>\n  <Name>John Doe</Name>\n  <Age>30</Age>\n</user>")
+}
+
+func Test_Encode(t *testing.T) {
+	r := New(Options{XMLIndent: true, XMLPrefix: defaultXMLPrefix})
+	usr := user{"John Doe", 30}
+	write := map[string]func(w http.ResponseWriter, status int, v interface{}) error{
+		FormatJSON: r.JSON,
+		FormatXML:  r.XML,
+		FormatYAML: r.YAML,
+		FormatTOML: r.TOML,
+	}
+
+	for format, fn := range write {
+		bs, err := r.Encode(format, usr)
+		checkNil(t, err)
+		res := httptest.NewRecorder()
+		checkNil(t, fn(res, http.StatusOK, usr))
+		checkBody(t, string(bs), res.Body.String())
+	}
+
+	_, err := r.Encode("csv", usr)
+	checkNotNil(t, err)
+}
+
+func Test_XML_XMLRoot(t *testing.T) {
+	r := New()
+	ns := xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: "urn:renderer:user"}
+
+	res := httptest.NewRecorder()
+	err := r.XML(res, http.StatusOK, XMLRoot("person", map[string]string{"name": "john", "age": "30"}, ns))
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), defaultXMLPrefix+`<person xmlns="urn:renderer:user"><age>30</age><name>john</name></person>`)
+
+	res = httptest.NewRecorder()
+	err = r.XML(res, http.StatusOK, XMLRoot("person", user{"John Doe", 30}))
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), defaultXMLPrefix+`<person><Name>John Doe</Name><Age>30</Age></person>`)
+}
+
+func Test_XML_XMLMapRoot(t *testing.T) {
+	r := New(Options{XMLMapRoot: "response"})
+	res := httptest.NewRecorder()
+	err := r.XML(res, http.StatusOK, map[string]string{"name": "john"})
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), defaultXMLPrefix+`<response><name>john</name></response>`)
+
+	r = New()
+	res = httptest.NewRecorder()
+	err = r.XML(res, http.StatusOK, map[string]string{"name": "john"})
+	checkNotNil(t, err)
+}
+
+func Test_XML_XMLHeader(t *testing.T) {
+	header := `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+	r := New(Options{XMLHeader: header})
+	res := httptest.NewRecorder()
+	err := r.XML(res, http.StatusOK, user{"John Doe", 30})
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), header+"<user><Name>John Doe</Name><Age>30</Age></user>")
+
+	r = New().XMLHeader("")
+	res = httptest.NewRecorder()
+	err = r.XML(res, http.StatusOK, user{"John Doe", 30})
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), "<user><Name>John Doe</Name><Age>30</Age></user>")
+}
+
+func Test_XMLMap(t *testing.T) {
+	r := New(Options{XMLPrefix: " "})
+	var err error
+
+	m := map[string]interface{}{
+		"name": "John Doe",
+		"age":  30,
+		"address": map[string]interface{}{
+			"zip":  "1207",
+			"city": "Dhaka",
+		},
+		"tags": []interface{}{"a", "b"},
+	}
+	expected := " <user><address><city>Dhaka</city><zip>1207</zip></address><age>30</age><name>John Doe</name><tags>a</tags><tags>b</tags></user>"
+
+	for i := 0; i < 3; i++ {
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.XMLMap(w, http.StatusOK, "user", m)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/xml-map", nil)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkContentType(t, res.Header().Get(ContentType), ContentXML+"; charset="+defaultCharSet)
+		checkBody(t, res.Body.String(), expected)
+	}
+}
+
+func Test_YAML(t *testing.T) {
+	r := New()
+	var err error
+
+	usr := user{"John Doe", 30}
+	expected := "name: John Doe\nage: 30\n"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.YAML(w, http.StatusOK, usr)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/yaml", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentYAML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_YAML_ordered(t *testing.T) {
+	r := New()
+	v := YAMLMapSlice{{Key: "name", Value: "John Doe"}, {Key: "age", Value: 30}, {Key: "address", Value: YAMLMapSlice{{Key: "zip", Value: "1200"}, {Key: "city", Value: "Dhaka"}}}}
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		res := httptest.NewRecorder()
+		err := r.YAML(res, http.StatusOK, v)
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		bodies = append(bodies, res.Body.String())
+	}
+	checkBody(t, bodies[0], "name: John Doe\nage: 30\naddress:\n  zip: \"1200\"\n  city: Dhaka\n")
+	checkBody(t, bodies[1], bodies[0])
+}
+
+func Test_TOML(t *testing.T) {
+	r := New()
+	var err error
+
+	usr := user{"John Doe", 30}
+	expected := "Name = \"John Doe\"\nAge = 30\n"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.TOML(w, http.StatusOK, usr)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/toml", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentTOML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_TOML_indent(t *testing.T) {
+	r := New(Options{
+		TOMLIndent: true,
+	})
+	var err error
+
+	v := map[string]interface{}{"server": map[string]interface{}{"port": 9000}}
+	expected := "[server]\n port = 9000\n"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.TOML(w, http.StatusOK, v)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/toml", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_SVG(t *testing.T) {
+	r := New()
+	var err error
+
+	svg := `<svg xmlns="http://www.w3.org/2000/svg"><circle r="10"/></svg>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.SVG(w, http.StatusOK, []byte(svg))
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/chart.svg", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentSVG)
+	checkBody(t, res.Body.String(), svg)
+}
+
+func Test_SVG_declaration(t *testing.T) {
+	r := New(Options{SVGDeclaration: true})
+	var err error
+
+	svg := `<svg xmlns="http://www.w3.org/2000/svg"><circle r="10"/></svg>`
+	expected := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + svg
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.SVG(w, http.StatusOK, []byte(svg))
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/chart.svg", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_DataURI(t *testing.T) {
+	r := New()
+	var err error
+	png := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.DataURI(w, http.StatusOK, png, "image/png")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/logo", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), "data:image/png;base64,iVBORw0KGgo=")
+
+	// embedded in a template
+	var buf strings.Builder
+	tmpl := template.Must(template.New("img").Funcs(baseFuncs()).Parse(`<img src="{{dataURI .logo "image/png"}}">`))
+	checkNil(t, tmpl.Execute(&buf, M{"logo": png}))
+	checkBody(t, buf.String(), `<img src="data:image/png;base64,iVBORw0KGgo=">`)
+}
+
+func Test_HTMLString(t *testing.T) {
+	r := New()
+	var err error
+
+	data := "<h1>Hello John</h1>"
+	expected := `<h1>Hello John</h1>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.HTMLString(w, http.StatusOK, data)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/htmlstring", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_HTMLString_CSP(t *testing.T) {
+	policy := "default-src 'self'"
+	for _, reportOnly := range []bool{false, true} {
+		r := New(Options{
+			CSP:           policy,
+			CSPReportOnly: reportOnly,
+		})
+		res := httptest.NewRecorder()
+		err := r.HTMLString(res, http.StatusOK, "<h1>Hello John</h1>")
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+
+		enforced, reported := policy, ""
+		if reportOnly {
+			enforced, reported = "", policy
+		}
+		checkBody(t, res.Header().Get(ContentSecurityPolicy), enforced)
+		checkBody(t, res.Header().Get(ContentSecurityPolicyReportOnly), reported)
+	}
+}
 
 func Test_HTML(t *testing.T) {
 	var err error
@@ -394,23 +1400,243 @@ func Test_HTML(t *testing.T) {
 		},
 	)
 
-	expected := `<html><head><title>Header</title></head>home</html>`
-
+	expected := `<html><head><title>Header</title></head>home</html>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.HTML(w, http.StatusOK, "homePage", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/html", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_HTML_ParseGlobPatterns(t *testing.T) {
+	var err error
+	perm := os.ModePerm
+	//create tmp html template directories for parsing
+	for _, dir := range []string{"views", "partials"} {
+		if _, err = os.Stat(dir); os.IsNotExist(err) {
+			os.Mkdir(dir, perm)
+		}
+		defer os.RemoveAll(dir)
+	}
+	ioutil.WriteFile("partials/nav.html", []byte(`{{define "nav"}}<nav>{{.}}</nav>{{end}}`), perm)
+	ioutil.WriteFile("views/index.html", []byte(`{{define "homePage"}}<html>{{template "nav" .}}home</html>{{end}}`), perm)
+	r := New(
+		Options{
+			ParseGlobPatterns: []string{"views/*.html", "partials/*.html"},
+		},
+	)
+
+	res := httptest.NewRecorder()
+	err = r.HTML(res, http.StatusOK, "homePage", "Home")
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `<html><nav>Home</nav>home</html>`)
+}
+
+func Test_Partial(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	header := `{{define "header"}}<head><title>{{.}}</title></head>{{end}}`
+	ioutil.WriteFile(dir+"/header.tmpl", []byte(header), perm)
+	index := `{{define "homePage"}}<html>{{template "header" .}}home</html>{{end}}`
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
+	r := New(
+		Options{
+			ParseGlobPattern: dir + "/*.tmpl",
+		},
+	)
+
+	res := httptest.NewRecorder()
+	err = r.Partial(res, http.StatusOK, "header", "Home")
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), `<head><title>Home</title></head>`)
+
+	res = httptest.NewRecorder()
+	err = r.Partial(res, http.StatusOK, "footer", nil)
+	checkNotNil(t, err)
+	checkBody(t, err.Error(), "renderer: template footer is not defined")
+	checkBody(t, res.Body.String(), "")
+}
+
+func Test_HTML_without_name_and_debug(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	header := `{{define "header"}}<head><title>Header</title></head>{{end}}`
+	ioutil.WriteFile(dir+"/header.tmpl", []byte(header), perm)
+	index := `{{define "homePage"}}<html>{{template "header"}}home</html>{{end}}`
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
+	r := New(
+		Options{
+			ParseGlobPattern: dir + "/*.tmpl",
+			Debug:            true,
+		},
+	)
+
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.HTML(w, http.StatusOK, "homePage", nil)
+		err = r.HTML(w, http.StatusOK, "", nil)
 	})
 
 	res := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/html", nil)
 	h.ServeHTTP(res, req)
 
+	checkNotNil(t, err)
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, res.Code)
+	}
+	checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
+}
+
+type captureLogger struct {
+	messages []string
+}
+
+func (l *captureLogger) Printf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func Test_Template_Logger(t *testing.T) {
+	f, err := ioutil.TempFile("", "logger-*.tpl")
 	checkNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
+	defer os.Remove(f.Name())
+	f.WriteString(`<p>{{.Email}}</p>`)
+	f.Close()
+
+	l := &captureLogger{}
+	r := New(Options{Debug: true, Logger: l})
+	res := httptest.NewRecorder()
+	err = r.Template(res, http.StatusOK, []string{f.Name()}, user{"John Doe", 30})
+	checkNotNil(t, err)
+	if len(l.messages) != 1 || !strings.Contains(l.messages[0], "Email") {
+		t.Errorf("logger should receive the execute error. got: %q", l.messages)
+	}
 }
 
-func Test_HTML_without_name_and_debug(t *testing.T) {
+func Test_HTML_Logger_parse_error(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(`{{define "homePage"}}<p>home</p>{{end}}`), perm)
+
+	l := &captureLogger{}
+	var handled error
+	r := New(Options{
+		ParseGlobPattern: dir + "/*.tmpl",
+		Debug:            true,
+		Logger:           l,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			handled = err
+			InternalServerError(w, req, err)
+		},
+	})
+
+	// the typo is picked by the reparse of Debug mode
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(`{{define "homePage"}}<p>{{.Name</p>{{end}}`), perm)
+	res := httptest.NewRecorder()
+	err = r.HTML(res, http.StatusOK, "homePage", nil)
+	checkNotNil(t, err)
+	if handled != err {
+		t.Errorf("error handler should receive the parse error. got: %v", handled)
+	}
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, res.Code)
+	}
+	if len(l.messages) != 1 || !strings.Contains(l.messages[0], "index.tmpl") {
+		t.Errorf("logger should receive the parse error once. got: %q", l.messages)
+	}
+	if r.globExec == nil || r.globExec.Lookup("homePage") == nil {
+		t.Error("the last parsed templates should be kept")
+	}
+}
+
+func Test_New_Logger_parse_error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parse-error")
+	checkNil(t, err)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(`{{define "homePage"}}<p>{{.Name</p>{{end}}`), 0644)
+
+	// the parse error of New is logged instead of stopping the process
+	l := &captureLogger{}
+	r := New(Options{ParseGlobPattern: dir + "/*.tmpl", Logger: l})
+	if len(l.messages) != 1 || !strings.Contains(l.messages[0], "index.tmpl") {
+		t.Errorf("logger should receive the glob parse error. got: %q", l.messages)
+	}
+	err = r.HTML(httptest.NewRecorder(), http.StatusOK, "homePage", nil)
+	checkNotNil(t, err)
+
+	l = &captureLogger{}
+	New(Options{TemplateDir: dir, TemplateExtension: "tmpl", Logger: l})
+	if len(l.messages) != 1 || !strings.Contains(l.messages[0], "index.tmpl") {
+		t.Errorf("logger should receive the template dir parse error. got: %q", l.messages)
+	}
+}
+
+func Test_Template_ErrorHandler(t *testing.T) {
+	f, err := ioutil.TempFile("", "error-*.tpl")
+	checkNil(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`<p>{{missing .}}</p>`)
+	f.Close()
+
+	var handled error
+	r := New(Options{
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			handled = err
+			w.Header().Set(ContentType, ContentText)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("something went wrong"))
+		},
+	})
+	res := httptest.NewRecorder()
+	err = r.Template(res, http.StatusOK, []string{f.Name()}, nil)
+	checkNotNil(t, err)
+	if handled != err {
+		t.Errorf("error handler should receive the error. got: %v", handled)
+	}
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, res.Code)
+	}
+	checkContentType(t, res.Header().Get(ContentType), ContentText)
+	checkBody(t, res.Body.String(), "something went wrong")
+
+	// InternalServerError is the default handler
+	r = New()
+	res = httptest.NewRecorder()
+	checkNotNil(t, r.Template(res, http.StatusOK, []string{f.Name()}, nil))
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, res.Code)
+	}
+	checkBody(t, res.Body.String(), http.StatusText(http.StatusInternalServerError)+"\n")
+}
+
+func Test_HTML_invalid_name(t *testing.T) {
 	var err error
 	dir := "htmls"
 	perm := os.ModePerm
@@ -431,90 +1657,394 @@ func Test_HTML_without_name_and_debug(t *testing.T) {
 	)
 
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.HTML(w, http.StatusOK, "", nil)
+		err = r.HTML(w, http.StatusOK, "about", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/html", nil)
+	h.ServeHTTP(res, req)
+
+	checkNotNil(t, err)
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, res.Code)
+	}
+	checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
+}
+
+func Test_HTML_with_funcs(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	index := `{{define "homePage"}}<p>{{currentUser}}</p>{{end}}`
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
+	r := New(
+		Options{
+			ParseGlobPattern: dir + "/*.tmpl",
+			FuncMap: []template.FuncMap{
+				{"currentUser": func() string { return "guest" }},
+			},
+		},
+	)
+
+	render := func(opts ...RenderOption) string {
+		res := httptest.NewRecorder()
+		checkNil(t, r.HTML(res, http.StatusOK, "homePage", nil, opts...))
+		return res.Body.String()
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"john", "jane"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			funcs := WithFuncs(template.FuncMap{"currentUser": func() string { return name }})
+			for i := 0; i < 50; i++ {
+				checkBody(t, render(funcs), "<p>"+name+"</p>")
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	checkBody(t, render(), "<p>guest</p>")
+}
+
+func Test_Template(t *testing.T) {
+	var err error
+	dir := "templates"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	index := `{{ define "title" }}An example layout{{ end }}{{ define "content" }}<h1>Hello {{.name | toUpper }}</h1>{{ end }}`
+	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
+	layout := `<html><head><title>{{ template "title" . }}</title></head><body>{{ template "content" . }}</body></html>`
+	ioutil.WriteFile(dir+"/layout.tmpl", []byte(layout), perm)
+	r := New()
+
+	expected := `<html><head><title>An example layout</title></head><body><h1>Hello JOHN DOE</h1></body></html>`
+
+	toUpper := func(s string) string {
+		return strings.ToUpper(s)
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.FuncMap(template.FuncMap{
+			"toUpper": toUpper,
+		})
+		err = r.Template(w, http.StatusOK, []string{"templates/layout.tmpl", "templates/index.tmpl"}, map[string]string{"name": "john doe"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/template", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_HTML_recursive_glob(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp nested html template directories for parsing
+	os.MkdirAll(dir+"/admin/users", perm)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/index.html", []byte(`<h1>{{.}}</h1>{{template "footer"}}`), perm)
+	ioutil.WriteFile(dir+"/admin/dashboard.html", []byte(`<h2>{{.}}</h2>`), perm)
+	ioutil.WriteFile(dir+"/admin/users/list.html", []byte(`<ul><li>{{.}}</li></ul>`), perm)
+	ioutil.WriteFile(dir+"/admin/users/partials.html", []byte(`{{define "footer"}}<footer>admin</footer>{{end}}`), perm)
+	ioutil.WriteFile(dir+"/admin/notes.txt", []byte(`not a template`), perm)
+
+	r := New(
+		Options{
+			ParseGlobPattern: dir + "/**/*.html",
+		},
+	)
+
+	cases := map[string]string{
+		"index.html":            `<h1>John</h1><footer>admin</footer>`,
+		"admin/dashboard.html":  `<h2>John</h2>`,
+		"admin/users/list.html": `<ul><li>John</li></ul>`,
+	}
+	for name, expected := range cases {
+		res := httptest.NewRecorder()
+		err = r.HTML(res, http.StatusOK, name, "John")
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkBody(t, res.Body.String(), expected)
+	}
+
+	res := httptest.NewRecorder()
+	err = r.HTML(res, http.StatusOK, "admin/notes.txt", "John")
+	checkNotNil(t, err)
+}
+
+func Test_View(t *testing.T) {
+	var err error
+	dir := "view"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	home := `{{define "title"}}Home{{end}}{{define "content"}}<h3>Home page</h3><ul><li><a href="/">Home</a></li><li><a href="/about">About Me</a></li></ul><p>Lorem ipsum dolor sit amet</p>{{end}}`
+	ioutil.WriteFile(dir+"/home.tpl", []byte(home), perm)
+	about := `{{define "title"}}About Me{{end}}{{define "content"}}<h2>This is About me page.</h2><ul>Lorem ipsum dolor sit amet, consectetur adipisicing elit,</ul><p><a href="/">Home</a></p>{{end}}`
+	ioutil.WriteFile(dir+"/about.tpl", []byte(about), perm)
+	base := `<html><head><title>{{block "title" .}} {{end}}</title></head><body>{{ template "content" . }}</body></html>`
+	ioutil.WriteFile(dir+"/base.lout", []byte(base), perm)
+
+	r := New(
+		Options{
+			TemplateDir: "view",
+			Debug:       true,
+		},
+	)
+
+	expected := `<html><head><title>Home</title></head><body><h3>Home page</h3><ul><li><a href="/">Home</a></li><li><a href="/about">About Me</a></li></ul><p>Lorem ipsum dolor sit amet</p></body></html>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.View(w, http.StatusOK, "home", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/template", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_FileSystem(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/base.lout":  {Data: []byte(`<html>{{template "content" .}}</html>`)},
+		"views/home.tpl":   {Data: []byte(`{{define "content"}}<h1>{{.}}</h1>{{end}}`)},
+		"html/index.html":  {Data: []byte(`{{define "indexPage"}}<p>{{.}}</p>{{template "footer"}}{{end}}`)},
+		"html/footer.html": {Data: []byte(`{{define "footer"}}<footer>renderer</footer>{{end}}`)},
+	}
+	r := New(Options{
+		FileSystem:       fsys,
+		TemplateDir:      "views",
+		ParseGlobPattern: "html/*.html",
+	})
+	var err error
+
+	res := httptest.NewRecorder()
+	err = r.View(res, http.StatusOK, "home", "John")
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `<html><h1>John</h1></html>`)
+
+	res = httptest.NewRecorder()
+	err = r.HTML(res, http.StatusOK, "indexPage", "John")
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), `<p>John</p><footer>renderer</footer>`)
+
+	res = httptest.NewRecorder()
+	err = r.Template(res, http.StatusOK, []string{"views/base.lout", "views/home.tpl"}, "John")
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), `<html><h1>John</h1></html>`)
+}
+
+func Test_RenderToString(t *testing.T) {
+	var err error
+	dir := "view"
+	perm := os.ModePerm
+	//create tmp html template directories for parsing
+	os.MkdirAll(dir+"/html", perm)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/base.lout", []byte(`<html>{{template "content" .}}</html>`), perm)
+	ioutil.WriteFile(dir+"/welcome.tpl", []byte(`{{define "content"}}<h1>Welcome {{.name}}</h1>{{end}}`), perm)
+	ioutil.WriteFile(dir+"/html/email.html", []byte(`{{define "email"}}<p>Hi {{.name}}</p>{{end}}`), perm)
+
+	r := New(Options{
+		TemplateDir:      dir,
+		ParseGlobPattern: dir + "/html/*.html",
 	})
+	data := M{"name": "John"}
+
+	res := httptest.NewRecorder()
+	checkNil(t, r.View(res, http.StatusOK, "welcome", data))
+	out, err := r.RenderToString("welcome", data)
+	checkNil(t, err)
+	checkBody(t, out, res.Body.String())
+
+	res = httptest.NewRecorder()
+	checkNil(t, r.HTML(res, http.StatusOK, "email", data))
+	bs, err := r.RenderToBytes("email", data)
+	checkNil(t, err)
+	checkBody(t, string(bs), res.Body.String())
+	checkBody(t, string(bs), `<p>Hi John</p>`)
+
+	_, err = r.RenderToString("missing", data)
+	checkNotNil(t, err)
+}
+
+func Test_View_MaxTemplateOutput(t *testing.T) {
+	var err error
+	dir := "view"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	home := `{{define "content"}}{{range .items}}<li>{{.}}</li>{{end}}{{end}}`
+	ioutil.WriteFile(dir+"/home.tpl", []byte(home), perm)
+	base := `<ul>{{ template "content" . }}</ul>`
+	ioutil.WriteFile(dir+"/base.lout", []byte(base), perm)
+
+	r := New(
+		Options{
+			TemplateDir:       "view",
+			MaxTemplateOutput: 1024,
+		},
+	)
+
+	res := httptest.NewRecorder()
+	err = r.View(res, http.StatusOK, "home", M{"items": make([]int, 10)})
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), "<ul>"+strings.Repeat("<li>0</li>", 10)+"</ul>")
+
+	res = httptest.NewRecorder()
+	err = r.View(res, http.StatusOK, "home", M{"items": make([]int, 10000)})
+	if err != ErrTemplateOutputTooLarge {
+		t.Errorf("expected error: %v, got: %v", ErrTemplateOutputTooLarge, err)
+	}
+	checkBody(t, res.Body.String(), http.StatusText(http.StatusInternalServerError)+"\n")
+}
 
-	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/html", nil)
-	h.ServeHTTP(res, req)
+func Test_Debug_reload(t *testing.T) {
+	var err error
+	dir := "view"
+	perm := os.ModePerm
+	//create tmp html template directories for parsing
+	os.MkdirAll(dir+"/html", perm)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/base.lout", []byte(`<html>{{template "content" .}}</html>`), perm)
+	ioutil.WriteFile(dir+"/home.tpl", []byte(`{{define "content"}}<h1>Home</h1>{{end}}`), perm)
+	ioutil.WriteFile(dir+"/html/index.html", []byte(`{{define "indexPage"}}<h1>Index</h1>{{end}}`), perm)
+
+	for _, debug := range []bool{true, false} {
+		r := New(Options{
+			TemplateDir:       dir,
+			ParseGlobPattern:  dir + "/html/*.html",
+			CacheRenderedHTML: true,
+			Debug:             debug,
+		})
 
-	checkNotNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+		res := httptest.NewRecorder()
+		err = r.View(res, http.StatusOK, "home", nil)
+		checkNil(t, err)
+		checkBody(t, res.Body.String(), `<html><h1>Home</h1></html>`)
+		res = httptest.NewRecorder()
+		err = r.HTML(res, http.StatusOK, "indexPage", nil)
+		checkNil(t, err)
+		checkBody(t, res.Body.String(), `<h1>Index</h1>`)
+
+		ioutil.WriteFile(dir+"/home.tpl", []byte(`{{define "content"}}<h1>Home changed</h1>{{end}}`), perm)
+		ioutil.WriteFile(dir+"/html/index.html", []byte(`{{define "indexPage"}}<h1>Index changed</h1>{{end}}`), perm)
+
+		wantView, wantHTML := `<html><h1>Home changed</h1></html>`, `<h1>Index changed</h1>`
+		if !debug {
+			// the templates parsed by New are kept
+			wantView, wantHTML = `<html><h1>Home</h1></html>`, `<h1>Index</h1>`
+		}
+		res = httptest.NewRecorder()
+		err = r.View(res, http.StatusOK, "home", nil)
+		checkNil(t, err)
+		checkBody(t, res.Body.String(), wantView)
+		res = httptest.NewRecorder()
+		err = r.HTML(res, http.StatusOK, "indexPage", nil)
+		checkNil(t, err)
+		checkBody(t, res.Body.String(), wantHTML)
+
+		ioutil.WriteFile(dir+"/home.tpl", []byte(`{{define "content"}}<h1>Home</h1>{{end}}`), perm)
+		ioutil.WriteFile(dir+"/html/index.html", []byte(`{{define "indexPage"}}<h1>Index</h1>{{end}}`), perm)
+	}
 }
 
-func Test_HTML_invalid_name(t *testing.T) {
+func Test_View_DetectHTMLCharset(t *testing.T) {
 	var err error
-	dir := "htmls"
+	dir := "view"
 	perm := os.ModePerm
 	//create tmp html template directory for parsing
 	if _, err = os.Stat(dir); os.IsNotExist(err) {
 		os.Mkdir(dir, perm)
 	}
 	defer os.RemoveAll(dir)
-	header := `{{define "header"}}<head><title>Header</title></head>{{end}}`
-	ioutil.WriteFile(dir+"/header.tmpl", []byte(header), perm)
-	index := `{{define "homePage"}}<html>{{template "header"}}home</html>{{end}}`
-	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
-	r := New(
-		Options{
-			ParseGlobPattern: dir + "/*.tmpl",
-			Debug:            true,
-		},
-	)
+	ioutil.WriteFile(dir+"/base.lout", []byte(`<html><head>{{template "meta" .}}</head></html>`), perm)
+	ioutil.WriteFile(dir+"/latin.tpl", []byte(`{{define "meta"}}<meta charset="ISO-8859-1">{{end}}`), perm)
+	ioutil.WriteFile(dir+"/legacy.tpl", []byte(`{{define "meta"}}<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">{{end}}`), perm)
+	ioutil.WriteFile(dir+"/plain.tpl", []byte(`{{define "meta"}}<title>Plain</title>{{end}}`), perm)
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.HTML(w, http.StatusOK, "about", nil)
+	r := New(Options{
+		TemplateDir:       dir,
+		DetectHTMLCharset: true,
 	})
+	cases := map[string]string{
+		"latin":  ContentHTML + "; charset=ISO-8859-1",
+		"legacy": ContentHTML + "; charset=windows-1252",
+		"plain":  ContentHTML + "; charset=" + defaultCharSet,
+	}
 
-	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/html", nil)
-	h.ServeHTTP(res, req)
-
-	checkNotNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	for name, contentType := range cases {
+		res := httptest.NewRecorder()
+		err = r.View(res, http.StatusOK, name, nil)
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkContentType(t, res.Header().Get(ContentType), contentType)
+	}
 }
 
-func Test_Template(t *testing.T) {
+func Test_ViewWithLayout(t *testing.T) {
 	var err error
-	dir := "templates"
+	dir := "view"
 	perm := os.ModePerm
 	//create tmp html template directory for parsing
 	if _, err = os.Stat(dir); os.IsNotExist(err) {
 		os.Mkdir(dir, perm)
 	}
 	defer os.RemoveAll(dir)
-	index := `{{ define "title" }}An example layout{{ end }}{{ define "content" }}<h1>Hello {{.name | toUpper }}</h1>{{ end }}`
-	ioutil.WriteFile(dir+"/index.tmpl", []byte(index), perm)
-	layout := `<html><head><title>{{ template "title" . }}</title></head><body>{{ template "content" . }}</body></html>`
-	ioutil.WriteFile(dir+"/layout.tmpl", []byte(layout), perm)
-	r := New()
-
-	expected := `<html><head><title>An example layout</title></head><body><h1>Hello JOHN DOE</h1></body></html>`
+	ioutil.WriteFile(dir+"/admin.lout", []byte(`<main class="admin">{{template "content" .}}</main>`), perm)
+	ioutil.WriteFile(dir+"/public.lout", []byte(`<main class="public">{{template "content" .}}</main>`), perm)
+	ioutil.WriteFile(dir+"/home.tpl", []byte(`{{define "content"}}<h1>{{.}}</h1>{{end}}`), perm)
 
-	toUpper := func(s string) string {
-		return strings.ToUpper(s)
+	r := New(Options{
+		TemplateDir: dir,
+	})
+	cases := map[string]string{
+		"admin":  `<main class="admin"><h1>John Doe</h1></main>`,
+		"public": `<main class="public"><h1>John Doe</h1></main>`,
 	}
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		r.FuncMap(template.FuncMap{
-			"toUpper": toUpper,
-		})
-		err = r.Template(w, http.StatusOK, []string{"templates/layout.tmpl", "templates/index.tmpl"}, map[string]string{"name": "john doe"})
-	})
+	for layout, body := range cases {
+		res := httptest.NewRecorder()
+		err = r.ViewWithLayout(res, http.StatusOK, layout, "home", "John Doe")
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset=utf-8")
+		checkBody(t, res.Body.String(), body)
+	}
 
 	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/template", nil)
-	h.ServeHTTP(res, req)
-
-	checkNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
+	checkNotNil(t, r.ViewWithLayout(res, http.StatusOK, "missing", "home", "John Doe"))
 }
 
-func Test_View(t *testing.T) {
+func Test_View_invalid_name(t *testing.T) {
 	var err error
 	dir := "view"
 	perm := os.ModePerm
@@ -525,8 +2055,6 @@ func Test_View(t *testing.T) {
 	defer os.RemoveAll(dir)
 	home := `{{define "title"}}Home{{end}}{{define "content"}}<h3>Home page</h3><ul><li><a href="/">Home</a></li><li><a href="/about">About Me</a></li></ul><p>Lorem ipsum dolor sit amet</p>{{end}}`
 	ioutil.WriteFile(dir+"/home.tpl", []byte(home), perm)
-	about := `{{define "title"}}About Me{{end}}{{define "content"}}<h2>This is About me page.</h2><ul>Lorem ipsum dolor sit amet, consectetur adipisicing elit,</ul><p><a href="/">Home</a></p>{{end}}`
-	ioutil.WriteFile(dir+"/about.tpl", []byte(about), perm)
 	base := `<html><head><title>{{block "title" .}} {{end}}</title></head><body>{{ template "content" . }}</body></html>`
 	ioutil.WriteFile(dir+"/base.lout", []byte(base), perm)
 
@@ -537,23 +2065,22 @@ func Test_View(t *testing.T) {
 		},
 	)
 
-	expected := `<html><head><title>Home</title></head><body><h3>Home page</h3><ul><li><a href="/">Home</a></li><li><a href="/about">About Me</a></li></ul><p>Lorem ipsum dolor sit amet</p></body></html>`
-
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.View(w, http.StatusOK, "home", nil)
+		err = r.View(w, http.StatusOK, "invalid template", nil)
 	})
 
 	res := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/template", nil)
 	h.ServeHTTP(res, req)
 
-	checkNil(t, err)
-	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
-	checkBody(t, res.Body.String(), expected)
+	checkNotNil(t, err)
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, res.Code)
+	}
+	checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
 }
 
-func Test_View_invalid_name(t *testing.T) {
+func Test_View_csrf_token(t *testing.T) {
 	var err error
 	dir := "view"
 	perm := os.ModePerm
@@ -562,29 +2089,31 @@ func Test_View_invalid_name(t *testing.T) {
 		os.Mkdir(dir, perm)
 	}
 	defer os.RemoveAll(dir)
-	home := `{{define "title"}}Home{{end}}{{define "content"}}<h3>Home page</h3><ul><li><a href="/">Home</a></li><li><a href="/about">About Me</a></li></ul><p>Lorem ipsum dolor sit amet</p>{{end}}`
-	ioutil.WriteFile(dir+"/home.tpl", []byte(home), perm)
-	base := `<html><head><title>{{block "title" .}} {{end}}</title></head><body>{{ template "content" . }}</body></html>`
+	form := `{{define "content"}}<form><input type="hidden" name="csrf" value="{{.csrfToken}}"></form>{{end}}`
+	ioutil.WriteFile(dir+"/form.tpl", []byte(form), perm)
+	base := `<html><body>{{ template "content" . }}</body></html>`
 	ioutil.WriteFile(dir+"/base.lout", []byte(base), perm)
 
 	r := New(
 		Options{
 			TemplateDir: "view",
-			Debug:       true,
 		},
 	)
 
+	expected := `<html><body><form><input type="hidden" name="csrf" value="secret-token"></form></body></html>`
+
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err = r.View(w, http.StatusOK, "invalid template", nil)
+		err = r.WithRequest(req).View(w, http.StatusOK, "form", nil)
 	})
 
 	res := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/template", nil)
+	req, _ := http.NewRequest("GET", "/form", nil)
+	req = req.WithContext(context.WithValue(req.Context(), CSRFTokenKey, "secret-token"))
 	h.ServeHTTP(res, req)
 
-	checkNotNil(t, err)
+	checkNil(t, err)
 	checkStatusOK(t, res.Code)
-	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
 }
 
 func Test_Binary_inline(t *testing.T) {
@@ -625,6 +2154,91 @@ func Test_Binary_attachment(t *testing.T) {
 	checkBody(t, res.Body.String(), "This is a long binary data")
 }
 
+// closeTracker describes a reader which tracks whether it is closed
+type closeTracker struct {
+	*strings.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func Test_Binary_auto_close_reader(t *testing.T) {
+	for _, autoClose := range []bool{true, false} {
+		var err error
+		r := New(Options{AutoCloseReader: autoClose})
+		file := &closeTracker{Reader: strings.NewReader("This is a long binary data")}
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			err = r.Binary(w, http.StatusOK, file, "abc.txt", false)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/bin", nil)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkBody(t, res.Body.String(), "This is a long binary data")
+		if file.closed != autoClose {
+			t.Errorf("reader closed should be %v", autoClose)
+		}
+	}
+}
+
+func Test_DetectContentType(t *testing.T) {
+	r := New(Options{DetectContentType: true})
+	png := "\x89PNG\r\n\x1a\n"
+	cases := []struct {
+		filename    string
+		data        string
+		contentType string
+	}{
+		{"logo.png", png, "image/png"},
+		{"notes.txt", "hello", "text/plain; charset=utf-8"},
+		{"logo", png, "image/png"},
+		{"blob", "\x00\x01\x02", ContentBinary},
+	}
+
+	for _, c := range cases {
+		res := httptest.NewRecorder()
+		err := r.Binary(res, http.StatusOK, strings.NewReader(c.data), c.filename, false)
+		checkNil(t, err)
+		checkContentType(t, res.Header().Get(ContentType), c.contentType)
+
+		res = httptest.NewRecorder()
+		err = r.File(res, http.StatusOK, struct{ io.Reader }{strings.NewReader(c.data)}, c.filename, true)
+		checkNil(t, err)
+		checkContentType(t, res.Header().Get(ContentType), c.contentType)
+	}
+
+	// Binary keeps the binary content type by default
+	res := httptest.NewRecorder()
+	err := New().Binary(res, http.StatusOK, strings.NewReader(png), "logo.png", false)
+	checkNil(t, err)
+	checkContentType(t, res.Header().Get(ContentType), ContentBinary+"; charset="+defaultCharSet)
+}
+
+func Test_File_auto_close_reader(t *testing.T) {
+	var err error
+	r := New(Options{AutoCloseReader: true})
+	file := &closeTracker{Reader: strings.NewReader("This is a long binary data")}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.File(w, http.StatusOK, file, "abc.txt", true)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/file-inline", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	if !file.closed {
+		t.Error("reader should be closed")
+	}
+}
+
 func Test_File_inline(t *testing.T) {
 	var err error
 	r := New()
@@ -661,6 +2275,95 @@ func Test_File_attachment(t *testing.T) {
 	checkBody(t, res.Body.String(), "This is a long binary data")
 }
 
+func Test_Binary_with_disposition(t *testing.T) {
+	var err error
+	r := New()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		file := strings.NewReader("This is a long binary data")
+		err = r.BinaryWithDisposition(w, http.StatusOK, file, `form-data; name="file"`)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/bin", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	if got := res.Header().Get(ContentDisposition); got != `form-data; name="file"` {
+		t.Errorf("content disposition missmatch. got: %s", got)
+	}
+	checkBody(t, res.Body.String(), "This is a long binary data")
+}
+
+func Test_File_with_disposition(t *testing.T) {
+	var err error
+	r := New()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		file := strings.NewReader("This is a long binary data")
+		err = r.FileWithDisposition(w, http.StatusOK, file, "inline; filename=abc.txt")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/file-disposition", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	if got := res.Header().Get(ContentDisposition); got != "inline; filename=abc.txt" {
+		t.Errorf("content disposition missmatch. got: %s", got)
+	}
+	checkBody(t, res.Body.String(), "This is a long binary data")
+}
+
+func Test_ServeImmutable(t *testing.T) {
+	var err error
+	r := New()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		file := strings.NewReader("body{color:red}")
+		err = r.ServeImmutable(w, http.StatusOK, file, "app.3f9a.css")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/assets/app.3f9a.css", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	if got := res.Header().Get(CacheControl); got != "public, max-age=31536000, immutable" {
+		t.Errorf("cache control missmatch. got: %s", got)
+	}
+	checkContentType(t, res.Header().Get(ContentType), "text/css; charset=utf-8")
+	checkBody(t, res.Body.String(), "body{color:red}")
+}
+
+func Test_File_download_digest(t *testing.T) {
+	var err error
+	r := New(Options{AddDigest: true})
+	file := "digest.txt"
+	ioutil.WriteFile(file, []byte("hello world"), os.ModePerm)
+	defer os.Remove(file)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.FileDownload(w, http.StatusOK, file, "hello")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/file-digest", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	if got := res.Header().Get(ContentMD5); got != "XrY7u+Ae7tCTyyK7j1rNww==" {
+		t.Errorf("Content-MD5 missmatch. got: %s", got)
+	}
+	if got := res.Header().Get(Digest); got != "SHA-256=uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=" {
+		t.Errorf("Digest missmatch. got: %s", got)
+	}
+}
+
 func Test_File_view(t *testing.T) {
 	var err error
 	r := New()
@@ -695,6 +2398,145 @@ func Test_File_download(t *testing.T) {
 	checkContentType(t, res.Header().Get(ContentType), r.opts.ContentText)
 }
 
+func Test_File_stat_headers(t *testing.T) {
+	r := New()
+	info, err := os.Stat("README.md")
+	checkNil(t, err)
+
+	for _, fn := range []func(w http.ResponseWriter, status int, fpath, name string) error{r.FileView, r.FileDownload} {
+		res := httptest.NewRecorder()
+		err = fn(res, http.StatusOK, "README.md", "readme")
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkBody(t, res.Header().Get(ContentLength), strconv.FormatInt(info.Size(), 10))
+		checkBody(t, res.Header().Get(LastModified), info.ModTime().UTC().Format(http.TimeFormat))
+		if int64(res.Body.Len()) != info.Size() {
+			t.Errorf("expected %d bytes, got %d", info.Size(), res.Body.Len())
+		}
+
+		// a missing file is not served
+		res = httptest.NewRecorder()
+		err = fn(res, http.StatusOK, "missing.md", "missing")
+		if !os.IsNotExist(err) {
+			t.Errorf("expected not exist error, got %v", err)
+		}
+		checkBody(t, res.Body.String(), "")
+	}
+}
+
+func Test_prettyParam(t *testing.T) {
+	cases := map[string][2]bool{
+		"/":                {false, false},
+		"/?pretty":         {true, true},
+		"/?pretty=1":       {true, true},
+		"/?pretty=false":   {false, true},
+		"/?format=pretty":  {true, true},
+		"/?format=compact": {false, true},
+	}
+	for url, want := range cases {
+		req, _ := http.NewRequest("GET", url, nil)
+		if pretty, ok := prettyParam(req); pretty != want[0] || ok != want[1] {
+			t.Errorf("prettyParam(%s). got: %v %v want: %v %v", url, pretty, ok, want[0], want[1])
+		}
+	}
+}
+
+func Test_WithRequest_HEAD(t *testing.T) {
+	r := New()
+	var err error
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.WithRequest(req).JSON(w, http.StatusOK, user{"John Doe", 30})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("HEAD", "/user", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset=utf-8")
+	checkBody(t, res.Body.String(), "")
+	if cl := res.Header().Get(ContentLength); cl != strconv.Itoa(len(`{"Name":"John Doe","Age":30}`)) {
+		t.Errorf("unexpected content length: got %q", cl)
+	}
+}
+
+func Test_WithPretty(t *testing.T) {
+	r := New()
+	v := struct {
+		Name string `json:"name" xml:"name" yaml:"name"`
+	}{"John Doe"}
+	expected := map[string]string{
+		"json": "{\n \"name\": \"John Doe\"\n}",
+		"xml":  defaultXMLPrefix + "<Name>\n <name>John Doe</name>\n</Name>",
+		"yaml": "name: John Doe\n",
+	}
+
+	for format, want := range expected {
+		var err error
+		h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch format {
+			case "json":
+				err = r.WithPretty(req).JSON(w, http.StatusOK, v)
+			case "xml":
+				err = r.WithPretty(req).XML(w, http.StatusOK, struct {
+					XMLName struct{} `xml:"Name"`
+					Name    string   `xml:"name"`
+				}{Name: v.Name})
+			case "yaml":
+				err = r.WithPretty(req).YAML(w, http.StatusOK, v)
+			}
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/user?pretty", nil)
+		h.ServeHTTP(res, req)
+
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkBody(t, res.Body.String(), want)
+	}
+
+	// the shared renderer is not changed
+	bs, _ := r.json(v)
+	checkBody(t, string(bs), `{"name":"John Doe"}`)
+
+	// compact YAML is in flow style
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/user?format=compact", nil)
+	checkNil(t, r.WithPretty(req).YAML(res, http.StatusOK, v))
+	checkBody(t, res.Body.String(), "{\"name\": \"John Doe\"}\n")
+}
+
+func Test_YAML_flow(t *testing.T) {
+	r := New(Options{YAMLFlow: true})
+	v := YAMLMapSlice{
+		{Key: "name", Value: "John: \"Doe\"\nJr"},
+		{Key: "age", Value: 30},
+		{Key: "admin", Value: false},
+		{Key: "tags", Value: []string{"a", "b"}},
+		{Key: "address", Value: YAMLMapSlice{{Key: "zip", Value: "1207"}, {Key: "city", Value: nil}}},
+		{Key: "empty", Value: []int{}},
+	}
+	bs, err := r.Encode(FormatYAML, v)
+	checkNil(t, err)
+	checkBody(t, string(bs), `{"name": "John: \"Doe\"\nJr", "age": 30, "admin": false, "tags": ["a", "b"], "address": {"zip": "1207", "city": null}, "empty": []}`+"\n")
+
+	// the flow style decodes to the same data
+	var flow, block YAMLMapSlice
+	checkNil(t, yaml.Unmarshal(bs, &flow))
+	bs, _ = marshalYAML(v)
+	checkNil(t, yaml.Unmarshal(bs, &block))
+	if !reflect.DeepEqual(flow, block) {
+		t.Errorf("flow style should decode like block style. got: %v want: %v", flow, block)
+	}
+
+	// scalars are kept
+	bs, err = r.Encode(FormatYAML, 1.5)
+	checkNil(t, err)
+	checkBody(t, string(bs), "1.5\n")
+}
+
 func Benchmark_NoContent(b *testing.B) {
 	r := New()
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -746,7 +2588,7 @@ func Benchmark_JSONP(b *testing.B) {
 }
 
 func Benchmark_XML(b *testing.B) {
-	r := New()
+	r := New(Options{XMLMapRoot: "user"})
 	v := map[string]string{"name": "john doe"}
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		for n := 0; n < b.N; n++ {
@@ -770,3 +2612,65 @@ func Benchmark_YAML(b *testing.B) {
 	req, _ := http.NewRequest("GET", "/", nil)
 	h.ServeHTTP(res, req)
 }
+
+func Benchmark_XMLMap_BufferHint(b *testing.B) {
+	m := map[string]interface{}{}
+	for i := 0; i < 5000; i++ {
+		m[fmt.Sprintf("item%d", i)] = strings.Repeat("john doe ", 10)
+	}
+	for _, hint := range []int{0, 1 << 20} {
+		b.Run(fmt.Sprintf("hint=%d", hint), func(b *testing.B) {
+			r := New(Options{BufferHint: hint})
+			res := httptest.NewRecorder()
+			res.Body = nil // discard the body
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				r.XMLMap(res, http.StatusOK, "items", m)
+			}
+		})
+	}
+}
+
+func Benchmark_View(b *testing.B) {
+	dir, err := ioutil.TempDir("", "view-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/base.lout", []byte(`<html><body>{{template "content" .}}</body></html>`), os.ModePerm)
+	ioutil.WriteFile(dir+"/home.tpl", []byte(`{{define "content"}}<ul>{{range .items}}<li>{{.}}</li>{{end}}</ul>{{end}}`), os.ModePerm)
+
+	items := make([]string, 500)
+	for i := range items {
+		items[i] = strings.Repeat("john doe ", 4)
+	}
+	r := New(Options{TemplateDir: dir})
+	data := M{"items": items}
+
+	// pooled is View rendering into the buffers of bufferPool
+	b.Run("pooled", func(b *testing.B) {
+		res := httptest.NewRecorder()
+		res.Body = nil // discard the body
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			r.View(res, http.StatusOK, "home", data)
+		}
+	})
+
+	// unpooled is the baseline rendering into a new buffer for every call as View did before bufferPool,
+	// compare with: go test -run NONE -bench Benchmark_View -benchmem
+	b.Run("unpooled", func(b *testing.B) {
+		res := httptest.NewRecorder()
+		res.Body = nil // discard the body
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			r.setHTMLHeaders(res)
+			bs, err := r.renderView(new(bytes.Buffer), "home", "", data, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			r.writeBody(res, http.StatusOK, bs)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// RequestID describes the X-Request-Id header
+const RequestID string = "X-Request-Id"
+
+// EchoRequestID set the X-Request-Id of the request on the response, a random UUID is generated when the request
+// does not have one. The request id is returned
+func EchoRequestID(w http.ResponseWriter, req *http.Request) string {
+	id := req.Header.Get(RequestID)
+	if id == "" {
+		id = newUUID()
+	}
+	w.Header().Set(RequestID, id)
+	return id
+}
+
+// newUUID return a random (version 4) UUID
+func newUUID() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(fmt.Errorf("renderer: %s", err.Error()))
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
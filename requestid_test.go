@@ -0,0 +1,63 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func Test_EchoRequestID(t *testing.T) {
+	r := New(Options{EchoRequestID: true})
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.WithRequest(req).JSON(w, http.StatusOK, user{"John Doe", 30})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/json", nil)
+	req.Header.Set(RequestID, "abc-123")
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	if got := res.Header().Get(RequestID); got != "abc-123" {
+		t.Errorf("request id should be echoed. got: %s", got)
+	}
+}
+
+func Test_EchoRequestID_generated(t *testing.T) {
+	r := New(Options{EchoRequestID: true})
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.WithRequest(req).String(w, http.StatusOK, "hello")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if got := res.Header().Get(RequestID); !uuid.MatchString(got) {
+		t.Errorf("request id should be a generated uuid. got: %s", got)
+	}
+}
+
+func Test_EchoRequestID_disabled(t *testing.T) {
+	r := New()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.WithRequest(req).String(w, http.StatusOK, "hello")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestID, "abc-123")
+	h.ServeHTTP(res, req)
+
+	if got := res.Header().Get(RequestID); got != "" {
+		t.Errorf("request id should not be echoed. got: %s", got)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const snapshotExt string = ".html"
+
+// snapshotPath return the snapshot file path of the template name inside SnapshotDir, the .html extension is
+// added unless the name already has it e.g: index.html of a glob set
+func (r *Render) snapshotPath(name string) (string, error) {
+	if !strings.HasSuffix(name, snapshotExt) {
+		name += snapshotExt
+	}
+	fn := filepath.Join(r.opts.SnapshotDir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(r.opts.SnapshotDir, fn)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("renderer: invalid snapshot name %s", name)
+	}
+	return fn, nil
+}
+
+// snapshot write the rendered output of the template name to SnapshotDir atomically when it is set, the renders
+// log the error and still serve the output as the snapshot is a side effect
+func (r *Render) snapshot(name string, bs []byte) error {
+	if r.opts.SnapshotDir == "" {
+		return nil
+	}
+	fn, err := r.snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file and rename it so readers never see a partial snapshot
+	tmp, err := ioutil.TempFile(filepath.Dir(fn), ".snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fn)
+}
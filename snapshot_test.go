@@ -0,0 +1,126 @@
+package renderer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_View_snapshot(t *testing.T) {
+	var err error
+	dir := "view"
+	snapshots := "snapshots"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	defer os.RemoveAll(snapshots)
+	home := `{{define "content"}}<h3>Home page</h3>{{end}}`
+	ioutil.WriteFile(dir+"/home.tpl", []byte(home), perm)
+	base := `<html><body>{{ template "content" . }}</body></html>`
+	ioutil.WriteFile(dir+"/base.lout", []byte(base), perm)
+
+	r := New(
+		Options{
+			TemplateDir: dir,
+			SnapshotDir: snapshots,
+		},
+	)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.View(w, http.StatusOK, "home", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+
+	bs, rerr := ioutil.ReadFile(snapshots + "/home.html")
+	checkNil(t, rerr)
+	checkBody(t, string(bs), res.Body.String())
+}
+
+func Test_ViewWithLayout_snapshot_and_cache(t *testing.T) {
+	var err error
+	dir := "view"
+	snapshots := "snapshots"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	defer os.RemoveAll(snapshots)
+	ioutil.WriteFile(dir+"/admin.lout", []byte(`<main class="admin">{{template "content" .}}</main>`), perm)
+	ioutil.WriteFile(dir+"/public.lout", []byte(`<main class="public">{{template "content" .}}</main>`), perm)
+	ioutil.WriteFile(dir+"/home.tpl", []byte(`{{define "content"}}<h1>{{.Title}}</h1>{{end}}`), perm)
+
+	r := New(
+		Options{
+			TemplateDir:       dir,
+			SnapshotDir:       snapshots,
+			CacheRenderedHTML: true,
+		},
+	)
+
+	// every layout is rendered twice so that the second render is served by the cache
+	for i := 0; i < 2; i++ {
+		for _, layout := range []string{"admin", "public"} {
+			res := httptest.NewRecorder()
+			err = r.ViewWithLayout(res, http.StatusOK, layout, "home", keyedProfile{profile{"1", "Home"}})
+			checkNil(t, err)
+			checkBody(t, res.Body.String(), `<main class="`+layout+`"><h1>Home</h1></main>`)
+
+			bs, rerr := ioutil.ReadFile(snapshots + "/home@" + layout + ".html")
+			checkNil(t, rerr)
+			checkBody(t, string(bs), res.Body.String())
+		}
+	}
+}
+
+func Test_snapshotPath_invalid(t *testing.T) {
+	r := New(Options{SnapshotDir: "snapshots"})
+	_, err := r.snapshotPath("../../etc/passwd")
+	checkNotNil(t, err)
+}
+
+func Test_HTML_snapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	checkNil(t, err)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/index.html", []byte(`{{define "index.html"}}<p>home</p>{{end}}`), 0644)
+
+	snapshots := dir + "/snapshots"
+	r := New(Options{ParseGlobPattern: dir + "/*.html", SnapshotDir: snapshots})
+	res := httptest.NewRecorder()
+	checkNil(t, r.HTML(res, http.StatusOK, "index.html", nil))
+
+	// the extension of the template name is not doubled
+	bs, err := ioutil.ReadFile(snapshots + "/index.html")
+	checkNil(t, err)
+	checkBody(t, string(bs), "<p>home</p>")
+	info, err := os.Stat(snapshots)
+	checkNil(t, err)
+	if perm := info.Mode().Perm(); perm&^0755 != 0 {
+		t.Errorf("snapshot dir should not be world writable. got: %v", perm)
+	}
+
+	// a snapshot failure is logged and the page is still served
+	ioutil.WriteFile(dir+"/blocked", nil, 0644)
+	l := &captureLogger{}
+	r = New(Options{ParseGlobPattern: dir + "/*.html", SnapshotDir: dir + "/blocked", Logger: l})
+	res = httptest.NewRecorder()
+	checkNil(t, r.HTML(res, http.StatusOK, "index.html", nil))
+	checkStatusOK(t, res.Code)
+	checkBody(t, res.Body.String(), "<p>home</p>")
+	if len(l.messages) != 1 {
+		t.Errorf("logger should receive the snapshot error. got: %q", l.messages)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ContentEventStream represents content type text/event-stream
+	ContentEventStream string = "text/event-stream"
+	// LastEventID describes the Last-Event-ID header sent by reconnecting EventSource clients
+	LastEventID string = "Last-Event-ID"
+)
+
+// EventSource describes a function returning the channel of the events following the last event id received
+// by the client, lastEventID is 0 on the first connection
+type EventSource func(lastEventID uint64) <-chan interface{}
+
+// writeEvent write an event with the id, strings are sent as is and other data as JSON
+func (r *Render) writeEvent(w http.ResponseWriter, id uint64, v interface{}) error {
+	var data string
+	if s, ok := v.(string); ok {
+		data = s
+	} else {
+		bs, err := r.json(v)
+		if err != nil {
+			return err
+		}
+		data = string(bs)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("id: " + strconv.FormatUint(id, 10) + "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// SSE stream the events of source as server-sent events, every event carries an auto-incrementing id so that
+// reconnecting clients resume after the Last-Event-ID they have received. Streaming stops when the channel is
+// closed or the request context is done
+func (r *Render) SSE(w http.ResponseWriter, req *http.Request, status int, source EventSource) error {
+	// an invalid Last-Event-ID restarts the stream
+	id, _ := strconv.ParseUint(strings.TrimSpace(req.Header.Get(LastEventID)), 10, 64)
+	events := source(id)
+
+	w.Header().Set(ContentType, r.withCharset(ContentEventStream))
+	w.Header().Set(CacheControl, "no-cache")
+	r.writeHeader(w, status)
+	flush(w)
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil
+		case v, ok := <-events:
+			if !ok {
+				return nil
+			}
+			id++
+			if err := r.writeEvent(w, id, v); err != nil {
+				return err
+			}
+			flush(w)
+		}
+	}
+}
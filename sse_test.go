@@ -0,0 +1,35 @@
+package renderer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_SSE(t *testing.T) {
+	r := New()
+	history := []interface{}{"connected", map[string]int{"count": 1}, "line 1\nline 2"}
+	source := func(lastEventID uint64) <-chan interface{} {
+		events := make(chan interface{}, len(history))
+		for _, e := range history[lastEventID:] {
+			events <- e
+		}
+		close(events)
+		return events
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events", nil)
+	err := r.SSE(res, req, http.StatusOK, source)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentEventStream+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), "id: 1\ndata: connected\n\nid: 2\ndata: {\"count\":1}\n\nid: 3\ndata: line 1\ndata: line 2\n\n")
+
+	// the reconnecting client resumes after the last received event
+	res = httptest.NewRecorder()
+	req.Header.Set(LastEventID, "2")
+	err = r.SSE(res, req, http.StatusOK, source)
+	checkNil(t, err)
+	checkBody(t, res.Body.String(), "id: 3\ndata: line 1\ndata: line 2\n\n")
+}
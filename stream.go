@@ -0,0 +1,322 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"time"
+)
+
+// flush flush the writer if it supports http.Flusher
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// StreamGzip stream the newline delimited data of src as text/plain response, the data is gzip compressed
+// when the request accepts gzip and every line is flushed to the client. Streaming stops when the request
+// context is done, note that a blocked read on src is not interrupted. The Digest trailer is sent when StreamDigest is set.
+func (r *Render) StreamGzip(w http.ResponseWriter, req *http.Request, status int, src io.Reader) error {
+	w.Header().Set(ContentType, r.opts.ContentText)
+	w.Header().Add(Vary, AcceptEncoding)
+
+	// the digest trailer is computed from the bytes sent, after the compression
+	var sum hash.Hash
+	var dst io.Writer = w
+	if r.opts.StreamDigest {
+		DeclareTrailers(w, Digest)
+		sum = sha256.New()
+		dst = io.MultiWriter(w, sum)
+	}
+
+	out := dst
+	var gz *gzip.Writer
+	if acceptsEncoding(req, encodingGzip) {
+		w.Header().Set(ContentEncoding, encodingGzip)
+		w.Header().Del(ContentLength)
+		gz = gzip.NewWriter(dst)
+		defer gz.Close()
+		out = gz
+	}
+	r.WithRequest(req).writeHeader(w, status)
+
+	ctx := req.Context()
+	br := bufio.NewReader(src)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, werr := out.Write(line); werr != nil {
+				return werr
+			}
+			if gz != nil {
+				if ferr := gz.Flush(); ferr != nil {
+					return ferr
+				}
+			}
+			flush(w)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if sum != nil {
+		SetTrailer(w, Digest, "SHA-256="+base64.StdEncoding.EncodeToString(sum.Sum(nil)))
+	}
+	return nil
+}
+
+// writeFrame write the payload prefixed with its 4-byte big-endian length
+func writeFrame(w io.Writer, payload []byte) error {
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(payload)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// JSONFrame write data as a length-prefixed JSON frame and flush it, it can be called repeatedly on the same
+// response. The Content-Type is set to application/octet-stream unless it is already set
+func (r *Render) JSONFrame(w http.ResponseWriter, v interface{}) error {
+	bs, err := r.json(v)
+	if err != nil {
+		return err
+	}
+	if w.Header().Get(ContentType) == "" {
+		w.Header().Set(ContentType, r.opts.ContentBinary)
+	}
+	if err = writeFrame(w, bs); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+// PageFunc describes a callback returning the items of a page, fewer items than limit ends the pagination
+type PageFunc func(offset, limit int) ([]interface{}, error)
+
+// TemplateStreamPaged execute the named template of the ParseGlobPattern set for every item pulled page by page
+// from fetch and stream it as html response, the response is flushed after every page
+func (r *Render) TemplateStreamPaged(w http.ResponseWriter, status int, name string, limit int, fetch PageFunc) error {
+	if limit <= 0 {
+		return errors.New("renderer: page limit must be positive")
+	}
+	_, exec, err := r.globSets()
+	if err != nil {
+		return err
+	}
+	if exec == nil || exec.Lookup(name) == nil {
+		return fmt.Errorf("renderer: template %s does not exist", name)
+	}
+
+	r.setHTMLHeaders(w)
+	r.writeHeader(w, status)
+
+	for offset := 0; ; offset += limit {
+		items, err := fetch(offset, limit)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err = exec.ExecuteTemplate(w, name, item); err != nil {
+				return err
+			}
+		}
+		flush(w)
+		if len(items) < limit {
+			return nil
+		}
+	}
+}
+
+// JSONStreamIter stream the items pulled from next as JSON array response without materializing the result set,
+// next returns false when exhausted. Since the status is already written when next fails mid stream, the array is
+// left unterminated so that clients never mistake a partial result for a complete one and the error is returned
+func (r *Render) JSONStreamIter(w http.ResponseWriter, status int, next func() (interface{}, bool, error)) error {
+	w.Header().Set(ContentType, r.opts.ContentJSON)
+	r.writeHeader(w, status)
+
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		v, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		bs, err := r.json(v)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			bs = append([]byte(","), bs...)
+		}
+		if _, err = w.Write(bs); err != nil {
+			return err
+		}
+		flush(w)
+	}
+	_, err := w.Write([]byte("]"))
+	return err
+}
+
+// lineJSON return a copy of the Render encoding every JSON value on a single line for the newline delimited
+// JSON streams, the other JSON options apply as usual
+func (r *Render) lineJSON() *Render {
+	c := *r
+	c.opts.JSONIndent = false
+	return &c
+}
+
+// LogEntry describes a structured log line of LogStream, the fields are encoded in the declared order
+type LogEntry struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// LogStream serve the log entries as newline delimited JSON, one entry per line e.g:
+// {"timestamp":"2017-10-01T08:30:00Z","level":"info","message":"started","fields":{"port":9000}}
+func (r *Render) LogStream(w http.ResponseWriter, status int, entries []LogEntry) error {
+	w.Header().Set(ContentType, r.withCharset(ContentNDJSON))
+	r.writeHeader(w, status)
+
+	lr := r.lineJSON()
+	for _, e := range entries {
+		bs, err := lr.json(e)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(append(bs, '\n')); err != nil {
+			return err
+		}
+		flush(w)
+	}
+	return nil
+}
+
+// JSONStream serve the items received from the channel as newline delimited JSON, every item is encoded and
+// flushed as it arrives so large collections are never buffered. Streaming stops when the channel is closed
+func (r *Render) JSONStream(w http.ResponseWriter, status int, items <-chan interface{}) error {
+	w.Header().Set(ContentType, r.withCharset(ContentNDJSON))
+	r.writeHeader(w, status)
+
+	lr := r.lineJSON()
+	for item := range items {
+		bs, err := lr.json(item)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(append(bs, '\n')); err != nil {
+			return err
+		}
+		flush(w)
+	}
+	return nil
+}
+
+const (
+	// ManifestYAML describes the YAML format of ManifestStream, the documents are separated by ---
+	ManifestYAML string = "yaml"
+	// ManifestNDJSON describes the newline delimited JSON format of ManifestStream
+	ManifestNDJSON string = "ndjson"
+)
+
+// ManifestStream serve the objects concatenated as a multi document YAML or as newline delimited JSON
+// depending on format e.g: the kubernetes manifests piped to kubectl apply -f -
+func (r *Render) ManifestStream(w http.ResponseWriter, status int, objs []interface{}, format string) error {
+	buf := r.newBuffer()
+	var contentType string
+	switch format {
+	case ManifestYAML:
+		contentType = r.opts.ContentYAML
+		for _, obj := range objs {
+			bs, err := marshalYAML(obj)
+			if err != nil {
+				return err
+			}
+			buf.WriteString("---\n")
+			buf.Write(bs)
+		}
+	case ManifestNDJSON:
+		contentType = r.withCharset(ContentNDJSON)
+		lr := r.lineJSON()
+		for _, obj := range objs {
+			bs, err := lr.json(obj)
+			if err != nil {
+				return err
+			}
+			buf.Write(append(bs, '\n'))
+		}
+	default:
+		return fmt.Errorf("renderer: unsupported manifest format %s", format)
+	}
+
+	w.Header().Set(ContentType, contentType)
+	return r.writeBody(w, status, buf.Bytes())
+}
+
+// LongPoll wait up to timeout for the data of wait and serve it as JSON response, No Content is served when
+// the timeout is reached. The context given to wait is done on timeout or when the request is canceled,
+// the request context error is returned when the client goes away before the data arrives
+func (r *Render) LongPoll(w http.ResponseWriter, req *http.Request, status int, wait func(ctx context.Context) (interface{}, error), timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+
+	type result struct {
+		v   interface{}
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := wait(ctx)
+		done <- result{v: v, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err == nil {
+			return r.WithRequest(req).unwrappedJSON(w, status, res.v)
+		}
+		if !errors.Is(res.err, context.DeadlineExceeded) || req.Context().Err() != nil {
+			return res.err
+		}
+	case <-ctx.Done():
+		if err := req.Context().Err(); err != nil {
+			return err
+		}
+	}
+	return r.WithRequest(req).NoContent(w)
+}
@@ -0,0 +1,384 @@
+package renderer
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_StreamGzip(t *testing.T) {
+	r := New()
+	var err error
+
+	data := "line 1\nline 2\nline 3\n"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.StreamGzip(w, req, http.StatusOK, strings.NewReader(data))
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/logs", nil)
+	req.Header.Set(AcceptEncoding, "gzip, deflate")
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentText+"; charset="+defaultCharSet)
+	if res.Header().Get(ContentEncoding) != "gzip" {
+		t.Error("content encoding should be gzip")
+	}
+	if !res.Flushed {
+		t.Error("stream should be flushed")
+	}
+
+	gr, gerr := gzip.NewReader(res.Body)
+	checkNil(t, gerr)
+	bs, _ := ioutil.ReadAll(gr)
+	checkBody(t, string(bs), data)
+}
+
+func Test_StreamGzip_not_accepted(t *testing.T) {
+	r := New()
+	var err error
+
+	data := "line 1\nline 2"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.StreamGzip(w, req, http.StatusOK, strings.NewReader(data))
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/logs", nil)
+	req.Header.Set(AcceptEncoding, "gzip;q=0")
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	if res.Header().Get(ContentEncoding) != "" {
+		t.Error("content encoding should be empty")
+	}
+	checkBody(t, res.Body.String(), data)
+}
+
+func Test_StreamGzip_canceled(t *testing.T) {
+	r := New()
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.StreamGzip(w, req, http.StatusOK, strings.NewReader("line 1\n"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/logs", nil)
+	h.ServeHTTP(res, req.WithContext(ctx))
+
+	checkNotNil(t, err)
+}
+
+func Test_JSONFrame(t *testing.T) {
+	r := New()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		checkNil(t, r.JSONFrame(w, user{"John Doe", 30}))
+		checkNil(t, r.JSONFrame(w, user{"Jane Doe", 25}))
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/frames", nil)
+	h.ServeHTTP(res, req)
+
+	checkStatusOK(t, res.Code)
+	if !res.Flushed {
+		t.Error("frames should be flushed")
+	}
+
+	var got []user
+	for {
+		var prefix [4]byte
+		if _, err := io.ReadFull(res.Body, prefix[:]); err == io.EOF {
+			break
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(prefix[:]))
+		_, err := io.ReadFull(res.Body, payload)
+		checkNil(t, err)
+		var u user
+		checkNil(t, json.Unmarshal(payload, &u))
+		got = append(got, u)
+	}
+
+	if len(got) != 2 || got[0].Name != "John Doe" || got[1].Name != "Jane Doe" {
+		t.Errorf("unexpected frames: %v", got)
+	}
+}
+
+func Test_TemplateStreamPaged(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	row := `{{define "row"}}<tr><td>{{.}}</td></tr>{{end}}`
+	ioutil.WriteFile(dir+"/row.tmpl", []byte(row), perm)
+	r := New(
+		Options{
+			ParseGlobPattern: dir + "/*.tmpl",
+		},
+	)
+
+	pages := [][]interface{}{{"a", "b"}, {"c"}}
+	calls := 0
+	fetch := func(offset, limit int) ([]interface{}, error) {
+		calls++
+		return pages[offset/limit], nil
+	}
+	expected := `<tr><td>a</td></tr><tr><td>b</td></tr><tr><td>c</td></tr>`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.TemplateStreamPaged(w, http.StatusOK, "row", 2, fetch)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/rows", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentHTML+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+	if calls != 2 {
+		t.Errorf("fetch should be called twice, called %d times", calls)
+	}
+	if !res.Flushed {
+		t.Error("pages should be flushed")
+	}
+}
+
+func Test_TemplateStreamPaged_Debug_concurrent(t *testing.T) {
+	var err error
+	dir := "htmls"
+	perm := os.ModePerm
+	//create tmp html template directory for parsing
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		os.Mkdir(dir, perm)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/row.tmpl", []byte(`{{define "row"}}<tr><td>{{.}}</td></tr>{{end}}`), perm)
+	r := New(
+		Options{
+			ParseGlobPattern: dir + "/*.tmpl",
+			Debug:            true,
+		},
+	)
+	fetch := func(offset, limit int) ([]interface{}, error) {
+		return []interface{}{"a"}, nil
+	}
+
+	// the templates are parsed again by every call, run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			checkNil(t, r.TemplateStreamPaged(httptest.NewRecorder(), http.StatusOK, "row", 2, fetch))
+		}()
+		go func() {
+			defer wg.Done()
+			checkNil(t, r.HTML(httptest.NewRecorder(), http.StatusOK, "row", "a"))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := r.RenderToBytes("row", "a")
+			checkNil(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+// iterOf return an iterator over the items which fails with err after the items when err is not nil
+func iterOf(err error, items ...interface{}) func() (interface{}, bool, error) {
+	i := 0
+	return func() (interface{}, bool, error) {
+		if i == len(items) {
+			return nil, false, err
+		}
+		i++
+		return items[i-1], true, nil
+	}
+}
+
+func Test_JSONStreamIter(t *testing.T) {
+	r := New()
+	var err error
+
+	next := iterOf(nil, user{"John Doe", 30}, user{"Jane Doe", 25}, user{"Jim Doe", 20})
+	expected := `[{"Name":"John Doe","Age":30},{"Name":"Jane Doe","Age":25},{"Name":"Jim Doe","Age":20}]`
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONStreamIter(w, http.StatusOK, next)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentJSON+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_JSONStreamIter_error(t *testing.T) {
+	r := New()
+	var err error
+
+	next := iterOf(errors.New("connection lost"), user{"John Doe", 30})
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSONStreamIter(w, http.StatusOK, next)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	h.ServeHTTP(res, req)
+
+	checkNotNil(t, err)
+	checkBody(t, res.Body.String(), `[{"Name":"John Doe","Age":30}`)
+}
+
+func Test_LogStream(t *testing.T) {
+	r := New()
+	var err error
+	ts := time.Date(2017, 10, 1, 8, 30, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: ts, Level: "info", Message: "server started", Fields: map[string]interface{}{"port": 9000, "env": "dev"}},
+		{Timestamp: ts.Add(time.Second), Level: "error", Message: "request failed"},
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.LogStream(w, http.StatusOK, entries)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/logs", nil)
+	h.ServeHTTP(res, req)
+
+	expected := `{"timestamp":"2017-10-01T08:30:00Z","level":"info","message":"server started","fields":{"env":"dev","port":9000}}
+{"timestamp":"2017-10-01T08:30:01Z","level":"error","message":"request failed"}
+`
+
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentNDJSON+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), expected)
+}
+
+func Test_LongPoll(t *testing.T) {
+	r := New()
+	events := make(chan string, 1)
+	wait := func(ctx context.Context) (interface{}, error) {
+		select {
+		case e := <-events:
+			return map[string]string{"event": e}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	events <- "created"
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/poll", nil)
+	err := r.LongPoll(res, req, http.StatusOK, wait, time.Second)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), r.opts.ContentJSON)
+	checkBody(t, res.Body.String(), `{"event":"created"}`)
+}
+
+func Test_LongPoll_timeout(t *testing.T) {
+	r := New(Options{EchoRequestID: true})
+	wait := func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/poll", nil)
+	req.Header.Set(RequestID, "req-1")
+	err := r.LongPoll(res, req, http.StatusOK, wait, 10*time.Millisecond)
+	checkNil(t, err)
+	if res.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, res.Code)
+	}
+	checkBody(t, res.Body.String(), "")
+	// the request bound headers are sent on timeout too
+	checkBody(t, res.Header().Get(RequestID), "req-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequestWithContext(ctx, "GET", "/poll", nil)
+	err = r.LongPoll(res, req, http.StatusOK, wait, time.Second)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled error, got %v", err)
+	}
+}
+
+func Test_ManifestStream(t *testing.T) {
+	r := New()
+	objs := []interface{}{
+		map[string]string{"kind": "Namespace"},
+		map[string]string{"kind": "Deployment"},
+	}
+	cases := map[string][2]string{
+		ManifestYAML:   {r.opts.ContentYAML, "---\nkind: Namespace\n---\nkind: Deployment\n"},
+		ManifestNDJSON: {ContentNDJSON + "; charset=" + defaultCharSet, "{\"kind\":\"Namespace\"}\n{\"kind\":\"Deployment\"}\n"},
+	}
+
+	for format, want := range cases {
+		res := httptest.NewRecorder()
+		err := r.ManifestStream(res, http.StatusOK, objs, format)
+		checkNil(t, err)
+		checkStatusOK(t, res.Code)
+		checkContentType(t, res.Header().Get(ContentType), want[0])
+		checkBody(t, res.Body.String(), want[1])
+	}
+
+	res := httptest.NewRecorder()
+	checkNotNil(t, r.ManifestStream(res, http.StatusOK, objs, "toml"))
+}
+
+func Test_JSONStream(t *testing.T) {
+	r := New()
+	items := make(chan interface{})
+	go func() {
+		defer close(items)
+		for _, name := range []string{"John", "Jane", "Joe"} {
+			items <- user{Name: name, Age: 30}
+		}
+	}()
+
+	res := httptest.NewRecorder()
+	err := r.JSONStream(res, http.StatusOK, items)
+	checkNil(t, err)
+	checkStatusOK(t, res.Code)
+	checkContentType(t, res.Header().Get(ContentType), ContentNDJSON+"; charset="+defaultCharSet)
+	checkBody(t, res.Body.String(), `{"Name":"John","Age":30}`+"\n"+`{"Name":"Jane","Age":30}`+"\n"+`{"Name":"Joe","Age":30}`+"\n")
+	if !res.Flushed {
+		t.Error("stream should be flushed")
+	}
+}
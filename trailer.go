@@ -0,0 +1,36 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Trailer describes the Trailer header
+const Trailer string = "Trailer"
+
+// DeclareTrailers announce the trailer names sent after the body, it must be called before the status is written
+// e.g: DeclareTrailers(w, "X-Checksum")
+func DeclareTrailers(w http.ResponseWriter, names ...string) {
+	for _, name := range names {
+		w.Header().Add(Trailer, http.CanonicalHeaderKey(name))
+	}
+}
+
+// SetTrailer set the trailer value once the body is written, a trailer which is not declared
+// by DeclareTrailers is sent using http.TrailerPrefix
+func SetTrailer(w http.ResponseWriter, name, value string) {
+	name = http.CanonicalHeaderKey(name)
+	for _, declared := range w.Header()[Trailer] {
+		for _, n := range strings.Split(declared, ",") {
+			if http.CanonicalHeaderKey(strings.TrimSpace(n)) == name {
+				w.Header().Set(name, value)
+				return
+			}
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+name, value)
+}
@@ -0,0 +1,70 @@
+package renderer
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_SetTrailer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		DeclareTrailers(w, "x-checksum")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Hello John"))
+		SetTrailer(w, "X-Checksum", "abc")
+		SetTrailer(w, "X-Undeclared", "xyz")
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	checkNil(t, err)
+	defer res.Body.Close()
+	bs, _ := ioutil.ReadAll(res.Body)
+
+	checkBody(t, string(bs), "Hello John")
+	checkBody(t, res.Trailer.Get("X-Checksum"), "abc")
+	checkBody(t, res.Trailer.Get("X-Undeclared"), "xyz")
+}
+
+func Test_StreamGzip_digest_trailer(t *testing.T) {
+	r := New(Options{
+		StreamDigest: true,
+	})
+	var err error
+	data := "line 1\nline 2\nline 3\n"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.StreamGzip(w, req, http.StatusOK, strings.NewReader(data))
+	}))
+	defer srv.Close()
+
+	for _, encoding := range []string{"gzip", "identity"} {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		req.Header.Set(AcceptEncoding, encoding)
+		// the transport must not decompress the body so that the digest can be verified
+		res, rerr := (&http.Transport{DisableCompression: true}).RoundTrip(req)
+		checkNil(t, rerr)
+		if _, ok := res.Trailer[Digest]; !ok {
+			t.Errorf("expected the %s trailer to be declared", Digest)
+		}
+		raw, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		checkNil(t, err)
+		sum := sha256.Sum256(raw)
+		checkBody(t, res.Trailer.Get(Digest), "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+
+		body := raw
+		if encoding == "gzip" {
+			gr, gerr := gzip.NewReader(strings.NewReader(string(raw)))
+			checkNil(t, gerr)
+			body, _ = ioutil.ReadAll(gr)
+		}
+		checkBody(t, string(body), data)
+	}
+}
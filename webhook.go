@@ -0,0 +1,36 @@
+// Copyright @2017 Saddam Hossain.  All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// webhookClient describes the client used to post to PostRenderWebhook
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// postWebhook post the JSON payload to PostRenderWebhook, a failure or non 2xx response is reported to PostRenderWebhookError
+func (r *Render) postWebhook(payload []byte) {
+	err := func() error {
+		res, err := webhookClient.Post(r.opts.PostRenderWebhook, r.opts.ContentJSON, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		io.Copy(ioutil.Discard, res.Body)
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("renderer: webhook %s responded with status %d", r.opts.PostRenderWebhook, res.StatusCode)
+		}
+		return nil
+	}()
+	if err != nil && r.opts.PostRenderWebhookError != nil {
+		r.opts.PostRenderWebhookError(err)
+	}
+}
@@ -0,0 +1,99 @@
+package renderer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_JSON_PostRenderWebhook(t *testing.T) {
+	received := make(chan string, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bs, _ := ioutil.ReadAll(req.Body)
+		received <- req.Header.Get(ContentType) + " " + string(bs)
+	}))
+	defer hook.Close()
+
+	r := New(Options{
+		PostRenderWebhook: hook.URL,
+		JSONPrefix:        ")]}',\n",
+	})
+	var err error
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = r.JSON(w, http.StatusCreated, M{"event": "user.created"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users", nil)
+	h.ServeHTTP(res, req)
+
+	checkNil(t, err)
+	if res.Code != http.StatusCreated {
+		t.Errorf("expected status: %d, got: %d", http.StatusCreated, res.Code)
+	}
+	select {
+	case got := <-received:
+		checkBody(t, got, r.opts.ContentJSON+` {"event":"user.created"}`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not invoked")
+	}
+}
+
+func Test_JSON_PostRenderWebhook_error(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer hook.Close()
+
+	failed := make(chan error, 1)
+	r := New(Options{
+		PostRenderWebhook:      hook.URL,
+		PostRenderWebhookError: func(err error) { failed <- err },
+	})
+
+	res := httptest.NewRecorder()
+	checkNil(t, r.JSON(res, http.StatusOK, M{"event": "user.created"}))
+	select {
+	case err := <-failed:
+		checkNotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook error callback was not invoked")
+	}
+}
+
+func Test_JSON_PostRenderWebhook_no_body(t *testing.T) {
+	received := make(chan struct{}, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received <- struct{}{}
+	}))
+	defer hook.Close()
+
+	r := New(Options{PostRenderWebhook: hook.URL, EnableETag: true})
+	v := M{"event": "user.created"}
+
+	// HEAD sends no body
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("HEAD", "/users", nil)
+	checkNil(t, r.WithRequest(req).JSON(res, http.StatusOK, v))
+
+	// 304 Not Modified sends no body
+	res = httptest.NewRecorder()
+	checkNil(t, r.JSON(res, http.StatusOK, v))
+	<-received
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Set(IfNoneMatch, res.Header().Get(ETag))
+	res = httptest.NewRecorder()
+	checkNil(t, r.WithRequest(req).JSON(res, http.StatusOK, v))
+	if res.Code != http.StatusNotModified {
+		t.Fatalf("expected status %d, got %d", http.StatusNotModified, res.Code)
+	}
+
+	select {
+	case <-received:
+		t.Error("webhook should not be invoked when no body is written")
+	case <-time.After(100 * time.Millisecond):
+	}
+}